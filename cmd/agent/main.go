@@ -7,40 +7,172 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
-	"github.com/aykay76/llmapi/internal/agent"
-	"github.com/aykay76/llmapi/pkg/ollama"
+	"github.com/aykay76/llmapi/pkg/agent"
 )
 
 func main() {
+	// Subcommands have their own flags
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serveMain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "models" {
+		os.Exit(modelsMain(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Command line flags
-	ollamaURL := flag.String("url", "http://localhost:11434", "Ollama API URL")
+	providerKind := flag.String("provider", "ollama", "Model backend: ollama, or openai for OpenAI-compatible servers (llama.cpp, vLLM, LM Studio)")
+	apiURL := flag.String("url", "", "API URL (default: "+defaultOllamaURL+" for ollama, "+defaultOpenAIURL+" for openai)")
+	apiKey := flag.String("api-key", "", "API key for the openai provider (default: $OPENAI_API_KEY)")
 	modelName := flag.String("model", "qwen3-coder:30b", "Model name to use")
 	promptDir := flag.String("prompts", "prompts", "Directory containing system prompt files")
 	systemPrompt := flag.String("system", "", "System prompt to use")
+	apiMode := flag.String("api", "chat", "API used for the model: chat or generate")
+	resume := flag.String("resume", "", "Name of a saved session to resume")
+	policyPath := flag.String("policy", "", "Command policy file (default: policy.json in the llmapi config directory, if present)")
+	shell := flag.Bool("shell", false, "Run commands through /bin/sh -c")
+	cmdTimeout := flag.Duration("cmd-timeout", 5*time.Minute, "Maximum run time of each command (0 for no limit)")
+	maxOutput := flag.Int("max-output", 32*1024, "Bytes of command stdout/stderr returned to the model (0 for no limit)")
+	allowRead := flag.String("allow-read", "", "Comma-separated directories actions may read outside the working directory")
+	eagerReads := flag.Bool("eager-reads", false, "Read files as soon as a read action streams in (with auto-execution)")
+	eventLog := flag.String("events", "", "Also write agent events as JSON lines to this file")
+	prompt := flag.String("p", "", "Run a single prompt and exit (\"-\" reads it from stdin)")
+	promptFile := flag.String("prompt-file", "", "Run the prompt in this file and exit")
+	batchPath := flag.String("batch", "", "Run every task in this JSONL file and write one result per line")
+	execute := flag.Bool("execute", false, "Apply the actions the model requests (non-interactive modes)")
+	format := flag.String("format", "text", "Progress format in non-interactive modes: text or json")
 	flag.Parse()
 
+	// Work out what to run before anything is printed
+	task, err := readPrompt(*prompt, *promptFile)
+	if err != nil {
+		log.Fatalf("Failed to read prompt: %v", err)
+	}
+	interactive := task == "" && *batchPath == ""
+	if task != "" && *batchPath != "" {
+		log.Fatal("-batch cannot be combined with -p or -prompt-file")
+	}
+	if *format != "text" && *format != "json" {
+		log.Fatalf("invalid -format value: %s (use 'text' or 'json')", *format)
+	}
+
+	// Status messages go to stderr unless the REPL owns the terminal
+	status := os.Stderr
+	if interactive {
+		status = os.Stdout
+	}
+
 	// Create Ollama client
-	client := ollama.NewClient(*ollamaURL)
-	client.SetTimeout(0) // Disable timeout for streaming
+	client, err := newProvider(*providerKind, *apiURL, *apiKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := []agent.Option{
+		agent.WithModel(*modelName),
+		agent.WithOutput(status),
+		agent.WithCommandOptions(agent.CommandOptions{
+			Shell:          *shell,
+			Timeout:        *cmdTimeout,
+			MaxOutputBytes: *maxOutput,
+		}),
+	}
+	if *allowRead != "" {
+		opts = append(opts, agent.WithReadRoots(strings.Split(*allowRead, ",")...))
+	}
+	if !interactive {
+		opts = append(opts, agent.WithAutoExecute(*execute))
+	}
+
+	// Render progress as text, or as JSON lines on stdout for a single prompt
+	var sink agent.EventSink = agent.NewTerminalRenderer(status)
+	if *format == "json" {
+		sink = agent.NewJSONRenderer(status)
+		if task != "" {
+			sink = agent.NewJSONRenderer(os.Stdout)
+		}
+	}
+	if *eventLog != "" {
+		f, err := os.Create(*eventLog)
+		if err != nil {
+			log.Fatalf("Failed to create event log: %v", err)
+		}
+		defer f.Close()
+		sink = agent.MultiSink(sink, agent.NewJSONRenderer(f))
+	}
+	opts = append(opts, agent.WithEventSink(sink))
+
+	// Load the command policy
+	if *policyPath == "" {
+		if path := agent.DefaultPolicyPath(); fileExists(path) {
+			*policyPath = path
+		}
+	}
+	if *policyPath != "" {
+		policy, err := agent.LoadCommandPolicy(*policyPath)
+		if err != nil {
+			log.Fatalf("Failed to load command policy: %v", err)
+		}
+		opts = append(opts, agent.WithCommandPolicy(policy))
+		fmt.Fprintf(status, "✓ Loaded command policy from: %s\n", *policyPath)
+	}
 
 	// Create agent
-	agentInstance := agent.NewAgent(client, *modelName)
+	infoCtx, cancelInfo := context.WithTimeout(context.Background(), modelInfoTimeout)
+	agentInstance := agent.NewWithContext(infoCtx, client, opts...)
+	cancelInfo()
+
+	switch mode := agent.APIMode(*apiMode); mode {
+	case agent.APIModeChat, agent.APIModeGenerate:
+		agentInstance.SetAPIMode(*modelName, mode)
+	default:
+		log.Fatalf("invalid -api value: %s (use 'chat' or 'generate')", *apiMode)
+	}
+
+	agentInstance.SetEagerReads(*eagerReads)
 
 	// Load system prompts from directory if specified
 	if *promptDir != "" {
 		if err := agentInstance.LoadSystemPromptDirectory(*promptDir); err != nil {
 			log.Printf("Warning: failed to load prompt directory: %v", err)
 		} else {
-			fmt.Printf("✓ Loaded system prompts from: %s\n", *promptDir)
+			fmt.Fprintf(status, "✓ Loaded system prompts from: %s\n", *promptDir)
 		}
 	}
 
+	// Resume a saved session if specified
+	if *resume != "" {
+		infoCtx, cancelInfo := context.WithTimeout(context.Background(), modelInfoTimeout)
+		err := agentInstance.LoadSession(infoCtx, *resume)
+		cancelInfo()
+		if err != nil {
+			log.Fatalf("Failed to resume session: %v", err)
+		}
+		fmt.Fprintf(status, "✓ Resumed session: %s\n", *resume)
+	}
+
 	// Set system prompt if specified
 	if *systemPrompt != "" {
 		agentInstance.SetSystemPrompt(*systemPrompt)
-		fmt.Println("✓ System prompt set")
+		fmt.Fprintln(status, "✓ System prompt set")
+	}
+
+	// Non-interactive modes stop on Ctrl+C or SIGTERM and report through the
+	// exit code
+	if !interactive {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		var code int
+		if *batchPath != "" {
+			code = runBatch(ctx, agentInstance, *batchPath, os.Stdout)
+		} else {
+			code = runPrompt(ctx, agentInstance, task, *format == "text")
+		}
+		stop()
+		os.Exit(code)
 	}
 
 	// Set up context with cancellation
@@ -62,3 +194,9 @@ func main() {
 		log.Fatalf("REPL error: %v", err)
 	}
 }
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aykay76/llmapi/pkg/agent"
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// modelsUsage describes the models subcommands
+const modelsUsage = `Usage: agent models pull [flags] <model>
+
+Subcommands:
+  pull    Download a model into the Ollama server, showing its progress`
+
+// modelsMain runs `agent models`, which manages the models of an Ollama
+// server, and returns the exit code
+func modelsMain(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, modelsUsage)
+		return exitFailure
+	}
+	switch args[0] {
+	case "pull":
+		return pullMain(args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown models subcommand: %s\n\n%s\n", args[0], modelsUsage)
+		return exitFailure
+	}
+}
+
+// pullMain runs `agent models pull`
+func pullMain(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("models pull", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	apiURL := fs.String("url", defaultOllamaURL, "Ollama API URL")
+	insecure := fs.Bool("insecure", false, "Allow insecure connections to the registry")
+	if err := fs.Parse(args); err != nil {
+		return exitFailure
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: agent models pull [flags] <model>")
+		return exitFailure
+	}
+	name := fs.Arg(0)
+
+	client := ollama.NewClient(*apiURL)
+	client.SetTimeout(0) // Pulls can take a long time; Ctrl+C cancels them
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	bar := agent.NewProgressBar(stdout)
+	err := client.StreamPullModelWithContext(ctx, &ollama.PullModelRequest{Name: name, Insecure: *insecure}, bar.Update)
+	bar.Finish()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to pull %s: %v\n", name, err)
+		return exitFailure
+	}
+	fmt.Fprintf(stdout, "✓ Pulled %s\n", name)
+	return exitOK
+}
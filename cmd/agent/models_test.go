@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestModelsPull(t *testing.T) {
+	var pulled string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		pulled = req.Name
+		if req.Name == "missing" {
+			fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"pulling aaa","digest":"sha256:aaa","total":10,"completed":5}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	}))
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	if code := modelsMain([]string{"pull", "-url", server.URL, "llama3"}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	if pulled != "llama3" || !strings.Contains(stdout.String(), "50%") || !strings.Contains(stdout.String(), "✓ Pulled llama3") {
+		t.Errorf("Unexpected pull of %q: %q", pulled, stdout.String())
+	}
+
+	stderr.Reset()
+	if code := modelsMain([]string{"pull", "-url", server.URL, "missing"}, &stdout, &stderr); code != exitFailure {
+		t.Errorf("Expected exit code %d for a failed pull, got %d", exitFailure, code)
+	}
+	if !strings.Contains(stderr.String(), "file does not exist") {
+		t.Errorf("Expected the server's error, got %q", stderr.String())
+	}
+
+	if code := modelsMain([]string{"remove", "llama3"}, &stdout, &stderr); code != exitFailure {
+		t.Errorf("Expected exit code %d for an unknown subcommand, got %d", exitFailure, code)
+	}
+}
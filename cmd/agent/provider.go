@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aykay76/llmapi/pkg/agent"
+	"github.com/aykay76/llmapi/pkg/ollama"
+	"github.com/aykay76/llmapi/pkg/openai"
+)
+
+// Default API URLs of the providers
+const (
+	defaultOllamaURL = "http://localhost:11434"
+	defaultOpenAIURL = "http://localhost:8080/v1"
+)
+
+// modelInfoTimeout bounds fetching the model's details when an agent is
+// created. The clients have no timeout of their own, so that long streams
+// are not cut off, and an unresponsive backend would block startup.
+const modelInfoTimeout = 30 * time.Second
+
+// newProvider creates the model backend selected with -provider. An empty
+// url selects the provider's default; an empty API key falls back to the
+// OPENAI_API_KEY environment variable.
+func newProvider(kind, url, apiKey string) (agent.Provider, error) {
+	switch kind {
+	case "ollama":
+		if url == "" {
+			url = defaultOllamaURL
+		}
+		client := ollama.NewClient(url)
+		client.SetTimeout(0) // Disable timeout for streaming
+		return client, nil
+	case "openai":
+		if url == "" {
+			url = defaultOpenAIURL
+		}
+		if apiKey == "" {
+			apiKey = os.Getenv("OPENAI_API_KEY")
+		}
+		client := openai.NewClient(url)
+		client.SetTimeout(0) // Disable timeout for streaming
+		client.SetAPIKey(apiKey)
+		return client, nil
+	default:
+		return nil, fmt.Errorf("invalid -provider value: %s (use 'ollama' or 'openai')", kind)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aykay76/llmapi/pkg/agent"
+)
+
+// Exit codes of the non-interactive modes
+const (
+	exitOK      = 0 // the model answered and every action succeeded
+	exitFailure = 1 // the request failed or an action did not succeed
+)
+
+// readPrompt returns the prompt given with -p, reading standard input for
+// "-", or the contents of the -prompt-file. It is empty when neither is set.
+func readPrompt(prompt, promptFile string) (string, error) {
+	if prompt != "" && promptFile != "" {
+		return "", fmt.Errorf("-p and -prompt-file cannot be combined")
+	}
+
+	var data []byte
+	var err error
+	switch {
+	case prompt == "-":
+		data, err = io.ReadAll(os.Stdin)
+	case prompt != "":
+		return prompt, nil
+	case promptFile != "":
+		data, err = os.ReadFile(promptFile)
+	default:
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return "", fmt.Errorf("prompt is empty")
+	}
+	return text, nil
+}
+
+// runPrompt sends a single prompt, streaming the response to stdout when
+// printResponse is set, and returns the process exit code
+func runPrompt(ctx context.Context, a *agent.Agent, prompt string, printResponse bool) int {
+	var onChunk func(string) error
+	if printResponse {
+		onChunk = func(chunk string) error {
+			_, err := fmt.Fprint(os.Stdout, chunk)
+			return err
+		}
+	}
+
+	err := a.SendMessage(ctx, prompt, onChunk)
+	if printResponse {
+		fmt.Println()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+
+	if failed := failures(a.TurnResults()); failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d action(s) did not succeed\n", failed)
+		return exitFailure
+	}
+	if pending := len(a.PendingActions()); pending > 0 {
+		fmt.Fprintf(os.Stderr, "%d action(s) not run; pass -execute to apply them\n", pending)
+	}
+	return exitOK
+}
+
+// failures counts the results that did not succeed
+func failures(results []agent.ActionResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.Status != agent.StatusSucceeded {
+			failed++
+		}
+	}
+	return failed
+}
+
+// batchTask is one line of a batch file. The prompt is taken from "prompt",
+// or built from "title" and "body" so backlog files such as requests.jsonl
+// can be run as they are.
+type batchTask struct {
+	ID        string `json:"id"`
+	RequestID string `json:"request_id"`
+	Prompt    string `json:"prompt"`
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+}
+
+// id returns the task's identifier, falling back to its line number
+func (t batchTask) id(line int) string {
+	switch {
+	case t.ID != "":
+		return t.ID
+	case t.RequestID != "":
+		return t.RequestID
+	default:
+		return fmt.Sprintf("line-%d", line)
+	}
+}
+
+// prompt returns the text sent to the model
+func (t batchTask) prompt() string {
+	if t.Prompt != "" {
+		return t.Prompt
+	}
+	return strings.TrimSpace(t.Title + "\n\n" + t.Body)
+}
+
+// batchResult is the record written for each task
+type batchResult struct {
+	ID             string               `json:"id"`
+	Status         string               `json:"status"` // succeeded, failed or error
+	Response       string               `json:"response,omitempty"`
+	Actions        []agent.ActionResult `json:"actions,omitempty"`
+	PendingActions int                  `json:"pending_actions,omitempty"`
+	Error          string               `json:"error,omitempty"`
+	DurationMS     int64                `json:"duration_ms"`
+}
+
+// runBatch runs every task in the JSONL file at path, each in a fresh
+// conversation, writes one result record per line to out and returns the
+// process exit code
+func runBatch(ctx context.Context, a *agent.Agent, path string, out io.Writer) int {
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitFailure
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(out)
+	code := exitOK
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		result := runTask(ctx, a, text, line)
+		if result.Status != "succeeded" {
+			code = exitFailure
+		}
+		if err := enc.Encode(result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitFailure
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+		return exitFailure
+	}
+	if ctx.Err() != nil {
+		return exitFailure
+	}
+	return code
+}
+
+// runTask runs one line of a batch file in a fresh conversation
+func runTask(ctx context.Context, a *agent.Agent, line string, lineNo int) batchResult {
+	var task batchTask
+	if err := json.Unmarshal([]byte(line), &task); err != nil {
+		return batchResult{ID: fmt.Sprintf("line-%d", lineNo), Status: "error", Error: fmt.Sprintf("invalid task: %v", err)}
+	}
+	result := batchResult{ID: task.id(lineNo)}
+	prompt := task.prompt()
+	if prompt == "" {
+		result.Status = "error"
+		result.Error = "task has no prompt"
+		return result
+	}
+
+	a.ClearHistory()
+	var response strings.Builder
+	start := time.Now()
+	err := a.SendMessage(ctx, prompt, func(chunk string) error {
+		response.WriteString(chunk)
+		return nil
+	})
+	result.DurationMS = time.Since(start).Milliseconds()
+	result.Response = response.String()
+	result.Actions = a.TurnResults()
+	result.PendingActions = len(a.PendingActions())
+
+	switch {
+	case err != nil:
+		result.Status = "error"
+		result.Error = err.Error()
+	case failures(result.Actions) > 0:
+		result.Status = "failed"
+	default:
+		result.Status = "succeeded"
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aykay76/llmapi/pkg/agent"
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// newTestAgent returns an agent talking to a server that answers every chat
+// request with reply
+func newTestAgent(t *testing.T, reply string) *agent.Agent {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		content, _ := json.Marshal(reply)
+		fmt.Fprintf(w, `{"message":{"role":"assistant","content":%s},"done":false}`+"\n", content)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	t.Cleanup(server.Close)
+
+	return agent.New(ollama.NewClient(server.URL),
+		agent.WithModel("test-model"),
+		agent.WithWorkDir(t.TempDir()),
+		agent.WithAutoExecute(true),
+		agent.WithEventSink(agent.EventSinkFunc(func(agent.Event) {})),
+	)
+}
+
+func TestReadPrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.txt")
+	os.WriteFile(path, []byte("  Fix the build\n"), 0644)
+
+	if got, err := readPrompt("", path); err != nil || got != "Fix the build" {
+		t.Errorf("Expected the file's prompt, got %q, %v", got, err)
+	}
+	if got, err := readPrompt("hello", ""); err != nil || got != "hello" {
+		t.Errorf("Expected the -p prompt, got %q, %v", got, err)
+	}
+	if _, err := readPrompt("hello", path); err == nil {
+		t.Error("Expected an error when -p and -prompt-file are combined")
+	}
+	if got, err := readPrompt("", ""); err != nil || got != "" {
+		t.Errorf("Expected no prompt, got %q, %v", got, err)
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	a := newTestAgent(t, "Reading it. <read_file><path>missing.txt</path></read_file>")
+
+	path := filepath.Join(t.TempDir(), "tasks.jsonl")
+	tasks := strings.Join([]string{
+		`{"id":"first","prompt":"Show missing.txt"}`,
+		``,
+		`{"request_id":"req-2","title":"Show it","body":"again"}`,
+		`not json`,
+	}, "\n")
+	os.WriteFile(path, []byte(tasks), 0644)
+
+	var out bytes.Buffer
+	// The read fails on every iteration, so keep the loop short
+	a.SetMaxIterations(1)
+	if code := runBatch(context.Background(), a, path, &out); code != exitFailure {
+		t.Errorf("Expected exit code %d, got %d", exitFailure, code)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 result records, got %d:\n%s", len(lines), out.String())
+	}
+
+	// Records are decoded loosely; ActionResult has no UnmarshalJSON
+	type record struct {
+		ID       string `json:"id"`
+		Status   string `json:"status"`
+		Response string `json:"response"`
+		Actions  []struct {
+			Status string `json:"status"`
+		} `json:"actions"`
+		Error string `json:"error"`
+	}
+	var results []record
+	for _, line := range lines {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Invalid record %q: %v", line, err)
+		}
+		if r.Status == "failed" && (len(r.Actions) != 1 || r.Actions[0].Status != "failed") {
+			t.Errorf("Expected one failed action in %s", line)
+		}
+		results = append(results, r)
+	}
+
+	if results[0].ID != "first" || results[0].Status != "failed" || !strings.Contains(results[0].Response, "Reading it.") {
+		t.Errorf("Unexpected first record: %+v", results[0])
+	}
+	if results[1].ID != "req-2" || results[1].Status != "failed" {
+		t.Errorf("Unexpected second record: %+v", results[1])
+	}
+	if results[2].ID != "line-4" || results[2].Status != "error" || results[2].Error == "" {
+		t.Errorf("Unexpected third record: %+v", results[2])
+	}
+}
+
+func TestRunPrompt_ExitCode(t *testing.T) {
+	a := newTestAgent(t, "Nothing to do.")
+	if code := runPrompt(context.Background(), a, "Hello", false); code != exitOK {
+		t.Errorf("Expected exit code %d, got %d", exitOK, code)
+	}
+
+	a = newTestAgent(t, "<read_file><path>missing.txt</path></read_file>")
+	a.SetMaxIterations(1)
+	if code := runPrompt(context.Background(), a, "Show missing.txt", false); code != exitFailure {
+		t.Errorf("Expected exit code %d, got %d", exitFailure, code)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/aykay76/llmapi/pkg/agent"
+	"github.com/aykay76/llmapi/pkg/server"
+)
+
+// serveMain runs `agent serve`, which exposes agent sessions over HTTP
+func serveMain(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	root := fs.String("root", "sessions", "Directory holding the working directory of each session")
+	providerKind := fs.String("provider", "ollama", "Model backend: ollama, or openai for OpenAI-compatible servers (llama.cpp, vLLM, LM Studio)")
+	apiURL := fs.String("url", "", "API URL (default: "+defaultOllamaURL+" for ollama, "+defaultOpenAIURL+" for openai)")
+	apiKey := fs.String("api-key", "", "API key for the openai provider (default: $OPENAI_API_KEY)")
+	modelName := fs.String("model", "qwen3-coder:30b", "Model name to use")
+	systemPrompt := fs.String("system", "", "System prompt for new sessions")
+	policyPath := fs.String("policy", "", "Command policy file (default: policy.json in the llmapi config directory, if present)")
+	shell := fs.Bool("shell", false, "Run commands through /bin/sh -c")
+	cmdTimeout := fs.Duration("cmd-timeout", 5*time.Minute, "Maximum run time of each command (0 for no limit)")
+	maxOutput := fs.Int("max-output", 32*1024, "Bytes of command stdout/stderr returned to the model (0 for no limit)")
+	fs.Parse(args)
+
+	client, err := newProvider(*providerKind, *apiURL, *apiKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *policyPath == "" {
+		if path := agent.DefaultPolicyPath(); fileExists(path) {
+			*policyPath = path
+		}
+	}
+	// Fail at startup rather than when the first session is created
+	if *policyPath != "" {
+		if _, err := agent.LoadCommandPolicy(*policyPath); err != nil {
+			log.Fatalf("Failed to load command policy: %v", err)
+		}
+	}
+
+	newAgent := func(workDir string) (*agent.Agent, error) {
+		opts := []agent.Option{
+			agent.WithModel(*modelName),
+			agent.WithWorkDir(workDir),
+			agent.WithSystemPrompt(*systemPrompt),
+			agent.WithCommandOptions(agent.CommandOptions{
+				Shell:          *shell,
+				Timeout:        *cmdTimeout,
+				MaxOutputBytes: *maxOutput,
+			}),
+			// There is no terminal to ask on, so commands the policy asks
+			// about are refused
+			agent.WithInput(strings.NewReader("")),
+		}
+		// Each session gets its own policy, so approvals never leak between
+		// them. Without a policy file every command is refused: clients may
+		// auto-execute, and nobody is there to review what they run.
+		policy := agent.NewCommandPolicy(agent.PolicyDeny)
+		if *policyPath != "" {
+			if policy, err = agent.LoadCommandPolicy(*policyPath); err != nil {
+				return nil, fmt.Errorf("failed to load command policy: %w", err)
+			}
+		}
+		opts = append(opts, agent.WithCommandPolicy(policy))
+		ctx, cancel := context.WithTimeout(context.Background(), modelInfoTimeout)
+		defer cancel()
+		return agent.NewWithContext(ctx, client, opts...), nil
+	}
+
+	handler, err := server.New(*root, newAgent)
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	httpServer := &http.Server{Addr: *addr, Handler: handler}
+
+	// Stop accepting requests on Ctrl+C or SIGTERM and let running ones finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Serving agent sessions on %s (working directories under %s)", *addr, *root)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server error: %v", err)
+	}
+}
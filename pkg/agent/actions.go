@@ -0,0 +1,598 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Action represents an executable action parsed from LLM output
+type Action interface {
+	Execute(ctx context.Context, workDir string) error
+	Validate() error
+	String() string
+}
+
+// ActionOutput is implemented by actions that produce output worth feeding
+// back to the model once they have run (file contents, command output, ...).
+type ActionOutput interface {
+	Output() string
+}
+
+// CreateFileAction represents a file creation action
+type CreateFileAction struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+func (a *CreateFileAction) Execute(ctx context.Context, workDir string) error {
+	fullPath, err := resolvePath(ctx, workDir, a.Path, true)
+	if err != nil {
+		return err
+	}
+
+	// Create directory if it doesn't exist
+	dir := filepath.Dir(fullPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	// Write file
+	if err := os.WriteFile(fullPath, []byte(a.Content), 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", fullPath, err)
+	}
+
+	return nil
+}
+
+func (a *CreateFileAction) Validate() error {
+	// Basic path validation - the sandbox does the full check on execution
+	if err := validateActionPath(a.Path); err != nil {
+		return fmt.Errorf("invalid file path: %w", err)
+	}
+	return nil
+}
+
+func (a *CreateFileAction) String() string {
+	return fmt.Sprintf("CREATE_FILE: %s (%d bytes)", a.Path, len(a.Content))
+}
+
+func (a *CreateFileAction) ActionName() string {
+	return "create_file"
+}
+
+func (a *CreateFileAction) targetPaths() []string {
+	return []string{a.Path}
+}
+
+func (a *CreateFileAction) bytesWritten() int {
+	return len(a.Content)
+}
+
+// ExecuteCommandAction represents a shell command execution
+type ExecuteCommandAction struct {
+	Command     string `json:"command"`
+	Description string `json:"description,omitempty"`
+
+	// Populated by Execute
+	stdout   cappedBuffer
+	stderr   cappedBuffer
+	exitCode int
+	duration time.Duration
+	timedOut bool
+}
+
+func (a *ExecuteCommandAction) Execute(ctx context.Context, workDir string) error {
+	opts := commandOptions(ctx)
+
+	// Without a shell, the command is split into words (honouring quotes)
+	// and run directly
+	var parts []string
+	if opts.Shell {
+		if strings.TrimSpace(a.Command) == "" {
+			return fmt.Errorf("empty command")
+		}
+		parts = []string{"/bin/sh", "-c", a.Command}
+	} else {
+		var err error
+		if parts, err = splitCommand(a.Command); err != nil {
+			return err
+		}
+	}
+
+	a.stdout.Reset(opts.MaxOutputBytes)
+	a.stderr.Reset(opts.MaxOutputBytes)
+	a.exitCode = 0
+	a.duration = 0
+	a.timedOut = false
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	// Output is still shown on the terminal, but also captured so it can be
+	// returned to the model
+	cmd := exec.CommandContext(ctx, parts[0], parts[1:]...)
+	cmd.Dir = workDir
+	cmd.Stdout = io.MultiWriter(outputWriter(ctx), &a.stdout)
+	cmd.Stderr = io.MultiWriter(outputWriter(ctx), &a.stderr)
+	killProcessGroup(cmd)
+	// Don't wait forever for output from processes that escaped the group
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	a.duration = time.Since(start)
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			a.exitCode = exitErr.ExitCode()
+		} else {
+			a.exitCode = -1
+		}
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			a.timedOut = true
+			return fmt.Errorf("command timed out after %s", opts.Timeout)
+		}
+		return fmt.Errorf("command failed: %w", err)
+	}
+
+	return nil
+}
+
+// ExitCode returns the exit code of the last run (-1 if the command could not be started)
+func (a *ExecuteCommandAction) ExitCode() int {
+	return a.exitCode
+}
+
+// Duration returns how long the last run took
+func (a *ExecuteCommandAction) Duration() time.Duration {
+	return a.duration
+}
+
+// Output returns the captured stdout/stderr, exit code and duration of the
+// last run
+func (a *ExecuteCommandAction) Output() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "exit code: %d\n", a.exitCode)
+	fmt.Fprintf(&b, "duration: %s\n", a.duration.Round(time.Millisecond))
+	if a.timedOut {
+		b.WriteString("timed out: true\n")
+	}
+	writeStream := func(name string, buf *cappedBuffer) {
+		if buf.Len() == 0 && buf.dropped == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%s:\n%s\n", name, strings.TrimRight(buf.String(), "\n"))
+		if buf.dropped > 0 {
+			fmt.Fprintf(&b, "[%s truncated: %d more bytes not shown]\n", name, buf.dropped)
+		}
+	}
+	writeStream("stdout", &a.stdout)
+	writeStream("stderr", &a.stderr)
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (a *ExecuteCommandAction) Validate() error {
+	if a.Command == "" {
+		return fmt.Errorf("command cannot be empty")
+	}
+	return nil
+}
+
+func (a *ExecuteCommandAction) String() string {
+	desc := a.Description
+	if desc == "" {
+		desc = "no description"
+	}
+	return fmt.Sprintf("EXECUTE_COMMAND: %s (%s)", a.Command, desc)
+}
+
+func (a *ExecuteCommandAction) ActionName() string {
+	return "execute_command"
+}
+
+// CreateDirectoryAction represents a directory creation action
+type CreateDirectoryAction struct {
+	Path string `json:"path"`
+}
+
+func (a *CreateDirectoryAction) Execute(ctx context.Context, workDir string) error {
+	fullPath, err := resolvePath(ctx, workDir, a.Path, true)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(fullPath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", fullPath, err)
+	}
+	return nil
+}
+
+func (a *CreateDirectoryAction) Validate() error {
+	if err := validateActionPath(a.Path); err != nil {
+		return fmt.Errorf("invalid directory path: %w", err)
+	}
+	return nil
+}
+
+func (a *CreateDirectoryAction) ActionName() string {
+	return "create_directory"
+}
+
+func (a *CreateDirectoryAction) String() string {
+	return fmt.Sprintf("CREATE_DIRECTORY: %s", a.Path)
+}
+
+func (a *CreateDirectoryAction) targetPaths() []string {
+	return []string{a.Path}
+}
+
+// ModifyFileAction represents a file modification action. By default the
+// search text must occur exactly once; Occurrence picks the nth match instead
+// and ReplaceAll replaces every match.
+type ModifyFileAction struct {
+	Path       string    `json:"path"`
+	Search     string    `json:"search"`
+	Replace    string    `json:"replace"`
+	Match      MatchMode `json:"match,omitempty"`
+	Occurrence int       `json:"occurrence,omitempty"`
+	ReplaceAll bool      `json:"replace_all,omitempty"`
+
+	// Populated by Execute
+	written int
+}
+
+func (a *ModifyFileAction) Execute(ctx context.Context, workDir string) error {
+	fullPath, err := resolvePath(ctx, workDir, a.Path, true)
+	if err != nil {
+		return err
+	}
+
+	// Read existing file
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", fullPath, err)
+	}
+
+	newContent, err := a.apply(string(content))
+	if err != nil {
+		return fmt.Errorf("%w in file %s", err, fullPath)
+	}
+
+	// Write back
+	if err := os.WriteFile(fullPath, []byte(newContent), 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", fullPath, err)
+	}
+	a.written = len(newContent)
+
+	return nil
+}
+
+// apply returns content with the search text replaced according to the
+// match mode and occurrence settings
+func (a *ModifyFileAction) apply(content string) (string, error) {
+	spans, mode := findMatches(content, a.Search, a.Match)
+	if len(spans) == 0 {
+		return "", fmt.Errorf("search string not found")
+	}
+
+	switch {
+	case a.ReplaceAll:
+	case a.Occurrence > 0:
+		if a.Occurrence > len(spans) {
+			return "", fmt.Errorf("occurrence %d requested but search string found %d time(s)", a.Occurrence, len(spans))
+		}
+		spans = spans[a.Occurrence-1 : a.Occurrence]
+	case len(spans) > 1:
+		return "", fmt.Errorf("search string is ambiguous: found %d times (give more context, an occurrence, or replace all)", len(spans))
+	}
+
+	// Replace from the end so earlier offsets stay valid
+	for i := len(spans) - 1; i >= 0; i-- {
+		replace := a.Replace
+		if mode == MatchWhitespace {
+			replace = reindent(replace, spans[i].indents)
+		}
+		content = content[:spans[i].start] + replace + content[spans[i].end:]
+	}
+	return content, nil
+}
+
+func (a *ModifyFileAction) Validate() error {
+	if err := validateActionPath(a.Path); err != nil {
+		return fmt.Errorf("invalid file path: %w", err)
+	}
+	if a.Search == "" {
+		return fmt.Errorf("search string cannot be empty")
+	}
+	if err := validateMatchMode(a.Match); err != nil {
+		return err
+	}
+	if a.Occurrence < 0 {
+		return fmt.Errorf("occurrence must be positive")
+	}
+	if a.Occurrence > 0 && a.ReplaceAll {
+		return fmt.Errorf("occurrence and replace all can't be combined")
+	}
+	return nil
+}
+
+func (a *ModifyFileAction) String() string {
+	var opts []string
+	if a.Match != "" && a.Match != MatchAuto {
+		opts = append(opts, string(a.Match)+" match")
+	}
+	if a.ReplaceAll {
+		opts = append(opts, "all occurrences")
+	} else if a.Occurrence > 0 {
+		opts = append(opts, fmt.Sprintf("occurrence %d", a.Occurrence))
+	}
+	if len(opts) > 0 {
+		return fmt.Sprintf("MODIFY_FILE: %s (%s)", a.Path, strings.Join(opts, ", "))
+	}
+	return fmt.Sprintf("MODIFY_FILE: %s", a.Path)
+}
+
+func (a *ModifyFileAction) ActionName() string {
+	return "modify_file"
+}
+
+func (a *ModifyFileAction) targetPaths() []string {
+	return []string{a.Path}
+}
+
+func (a *ModifyFileAction) bytesWritten() int {
+	return a.written
+}
+
+// ReadFileAction represents a file read request (returns content to LLM context)
+type ReadFileAction struct {
+	Path string `json:"path"`
+
+	// Populated by Execute
+	content string
+}
+
+func (a *ReadFileAction) Execute(ctx context.Context, workDir string) error {
+	fullPath, err := resolvePath(ctx, workDir, a.Path, false)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", fullPath, err)
+	}
+
+	// Keep the content so it can be added back to the LLM context
+	a.content = string(content)
+	fmt.Fprintf(outputWriter(ctx), "  (read %d bytes)\n", len(content))
+	return nil
+}
+
+// Output returns the file content read by the last run
+func (a *ReadFileAction) Output() string {
+	return a.content
+}
+
+func (a *ReadFileAction) Validate() error {
+	if err := validateActionPath(a.Path); err != nil {
+		return fmt.Errorf("invalid file path: %w", err)
+	}
+	return nil
+}
+
+func (a *ReadFileAction) String() string {
+	return fmt.Sprintf("READ_FILE: %s", a.Path)
+}
+
+func (a *ReadFileAction) ActionName() string {
+	return "read_file"
+}
+
+// ActionParser parses LLM output to extract action tags
+type ActionParser struct {
+	registry        *ActionRegistry
+	jsonBlockRegex  *regexp.Regexp
+	fencedCodeRegex *regexp.Regexp
+}
+
+// NewActionParser creates a parser for the built-in actions
+func NewActionParser() *ActionParser {
+	return NewRegistryParser(DefaultActionRegistry())
+}
+
+// NewRegistryParser creates a parser for the actions in a registry
+func NewRegistryParser(registry *ActionRegistry) *ActionParser {
+	return &ActionParser{
+		registry: registry,
+		// Matches fenced code blocks containing JSON: ```json {...} ``` or ``` {...} ```
+		jsonBlockRegex:  regexp.MustCompile("(?s)```(?:json)?\\s*(\\{.*?\\}|\\[.*?\\])\\s*```"),
+		fencedCodeRegex: regexp.MustCompile("(?s)```(\\w+)?\\s*(.*?)\\s*```"),
+	}
+}
+
+// Registry returns the registry of actions the parser recognizes
+func (p *ActionParser) Registry() *ActionRegistry {
+	return p.registry
+}
+
+// Parse extracts all actions from the LLM response
+func (p *ActionParser) Parse(response string) []Action {
+	return append(p.parseBlocks(response), p.parseTags(response)...)
+}
+
+// parseBlocks extracts the actions given as fenced code blocks: JSON
+// descriptions of files and code explicitly marked for file creation
+func (p *ActionParser) parseBlocks(response string) []Action {
+	var actions []Action
+
+	// First, attempt to find fenced JSON blocks and parse them. This lets
+	// models return structured JSON instead of XML-style tags.
+	for _, match := range p.jsonBlockRegex.FindAllStringSubmatch(response, -1) {
+		if len(match) >= 2 {
+			block := strings.TrimSpace(match[1])
+			var parsed interface{}
+			if err := json.Unmarshal([]byte(block), &parsed); err == nil {
+				// handle array of file objects
+				switch v := parsed.(type) {
+				case []interface{}:
+					for _, item := range v {
+						if obj, ok := item.(map[string]interface{}); ok {
+							// common keys: path/name and content
+							path := ""
+							content := ""
+							if pth, ok := obj["path"].(string); ok {
+								path = pth
+							} else if nm, ok := obj["name"].(string); ok {
+								path = nm
+							}
+							if cnt, ok := obj["content"].(string); ok {
+								content = cnt
+							} else if cnt, ok := obj["body"].(string); ok {
+								content = cnt
+							}
+							if path != "" && content != "" {
+								actions = append(actions, &CreateFileAction{Path: strings.TrimSpace(path), Content: content})
+							}
+						}
+					}
+				case map[string]interface{}:
+					// look for top-level keys naming a registered action, or
+					// "create_files"/"files"
+					for key, val := range v {
+						lk := strings.ToLower(key)
+						if def, ok := p.registry.Lookup(lk); ok {
+							if obj, ok := val.(map[string]interface{}); ok {
+								actions = append(actions, def.ParseJSON(obj))
+							} else if patch, ok := val.(string); ok && lk == "apply_patch" && patch != "" {
+								actions = append(actions, &ApplyPatchAction{Patch: patch})
+							}
+						} else if lk == "create_files" || lk == "files" {
+							if arr, ok := val.([]interface{}); ok {
+								for _, item := range arr {
+									if obj, ok := item.(map[string]interface{}); ok {
+										path := ""
+										content := ""
+										if pth, ok := obj["path"].(string); ok {
+											path = pth
+										} else if nm, ok := obj["name"].(string); ok {
+											path = nm
+										}
+										if cnt, ok := obj["content"].(string); ok {
+											content = cnt
+										} else if cnt, ok := obj["body"].(string); ok {
+											content = cnt
+										}
+										if path != "" && content != "" {
+											actions = append(actions, &CreateFileAction{Path: strings.TrimSpace(path), Content: content})
+										}
+									}
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Next, detect generic fenced code blocks (```html, ```js, etc.) that are
+	// explicitly marked for file creation. We no longer auto-create files from
+	// all code blocks to avoid creating files from example code.
+	nameCount := map[string]int{}
+	for _, match := range p.fencedCodeRegex.FindAllStringSubmatch(response, -1) {
+		if len(match) < 3 {
+			continue
+		}
+		lang := strings.ToLower(strings.TrimSpace(match[1]))
+		body := match[2]
+
+		// Only process code blocks that are explicitly marked for file creation
+		var filename string
+		// Look for the create-file marker in comments based on language
+		var markerMatch []string
+		switch lang {
+		case "html":
+			// HTML comment
+			markerMatch = regexp.MustCompile(`(?i)<!--\s*@create-file:\s*(\S+)\s*-->`).FindStringSubmatch(body)
+		case "css", "js", "javascript", "typescript", "java", "c", "cpp", "go":
+			// Block comment
+			markerMatch = regexp.MustCompile(`(?s)/\*\s*@create-file:\s*(\S+)\s*\*/`).FindStringSubmatch(body)
+			if markerMatch == nil {
+				// Line comment
+				markerMatch = regexp.MustCompile(`(?m)^[ \t]*//\s*@create-file:\s*(\S+)`).FindStringSubmatch(body)
+			}
+		default:
+			// Hash comment for other languages
+			markerMatch = regexp.MustCompile(`(?m)^[ \t]*#\s*@create-file:\s*(\S+)`).FindStringSubmatch(body)
+		}
+
+		if markerMatch != nil && len(markerMatch) > 1 {
+			filename = markerMatch[1]
+		}
+
+		// Skip if no explicit filename was provided
+		if filename == "" {
+			continue
+		}
+
+		// Sanitize filename (basic) and ensure uniqueness
+		filename = strings.TrimSpace(filename)
+		filename = strings.ReplaceAll(filename, "..", "")
+		base := filename
+		if cnt := nameCount[base]; cnt > 0 {
+			ext := filepath.Ext(base)
+			nameOnly := strings.TrimSuffix(base, ext)
+			filename = fmt.Sprintf("%s.%d%s", nameOnly, cnt, ext)
+		}
+		nameCount[base]++
+
+		// Emit create file action
+		actions = append(actions, &CreateFileAction{Path: filename, Content: body})
+	}
+
+	return actions
+}
+
+// parseTags extracts the actions given as XML-style tags
+func (p *ActionParser) parseTags(response string) []Action {
+	return p.registry.parseTags(response)
+}
+
+// trimBlankLines removes blank lines and trailing whitespace around text
+// while keeping the indentation of its first line
+func trimBlankLines(text string) string {
+	text = strings.TrimRight(text, " \t\r\n")
+	for {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 || strings.TrimSpace(text[:i]) != "" {
+			return text
+		}
+		text = text[i+1:]
+	}
+}
+
+// trimPatchBody removes the blank lines and markdown fence a model may put
+// around a diff. Leading spaces are kept since they mark context lines.
+func trimPatchBody(patch string) string {
+	patch = strings.Trim(patch, "\r\n")
+	if strings.HasPrefix(patch, "```") {
+		if i := strings.IndexByte(patch, '\n'); i >= 0 {
+			patch = patch[i+1:]
+		}
+		patch = strings.TrimSuffix(strings.TrimRight(patch, "\r\n \t"), "```")
+		patch = strings.Trim(patch, "\r\n")
+	}
+	return patch
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -279,3 +280,58 @@ func new() {
 		t.Errorf("Expected ModifyFileAction, got %T", actions[0])
 	}
 }
+
+func TestReadFileAction_Output(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte("port: 8080"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	action := &ReadFileAction{Path: "config.yaml"}
+	if err := action.Execute(context.Background(), tmpDir); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if action.Output() != "port: 8080" {
+		t.Errorf("Expected output 'port: 8080', got '%s'", action.Output())
+	}
+}
+
+func TestFormatActionResults(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tmpDir, "notes.txt"), []byte("remember the milk"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	actions := []Action{
+		&ReadFileAction{Path: "notes.txt"},
+		&ReadFileAction{Path: "missing.txt"},
+	}
+
+	results, _ := ExecuteActions(context.Background(), actions, tmpDir)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Err != nil {
+		t.Errorf("Expected first action to succeed, got %v", results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("Expected second action to fail")
+	}
+
+	result := FormatResults(results)
+
+	for _, want := range []string{
+		"[1/2] READ_FILE: notes.txt",
+		"status: succeeded",
+		"remember the milk",
+		"[2/2] READ_FILE: missing.txt",
+		"status: failed",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected result to contain %q, got:\n%s", want, result)
+		}
+	}
+}
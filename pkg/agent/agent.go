@@ -0,0 +1,1348 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// Agent represents a coding agent that can interact with an LLM
+type Agent struct {
+	client              Provider
+	systemPrompts       map[string]string
+	modelName           string
+	modelParams         *ModelParameters
+	conversationHistory []ollama.ChatMessage
+	systemPrompt        string
+	workDir             string
+	readRoots           []string
+	autoExecuteActions  bool
+	actionParser        *ActionParser
+	pendingActions      []Action
+	pendingFromTools    bool
+	lastResponseStats   *ollama.StreamStats
+	maxIterations       int
+	nativeTools         bool
+	apiModes            map[string]APIMode
+	compaction          CompactionStrategy
+	keepRecent          int
+	summaryModel        string
+	sessionDir          string
+	turnStats           []TurnStats
+	journal             *Journal
+	rollbackOnFailure   bool
+	commandPolicy       *CommandPolicy
+	commandOptions      CommandOptions
+	reviewActions       bool
+	approver            ApproveFunc
+	eagerReads          bool
+	prefetched          map[Action]error
+	lastResults         []ActionResult
+	turnResults         []ActionResult
+	input               *bufio.Reader
+	out                 io.Writer
+	events              EventSink
+}
+
+// APIMode selects the Ollama endpoint used to talk to a model
+type APIMode string
+
+const (
+	// APIModeChat uses /api/chat with role-separated messages (the default)
+	APIModeChat APIMode = "chat"
+	// APIModeGenerate flattens the conversation into a single /api/generate
+	// prompt, for models or servers whose chat endpoint misbehaves
+	APIModeGenerate APIMode = "generate"
+)
+
+// defaultMaxIterations bounds how many times the model is re-invoked with
+// action results before control returns to the user
+const defaultMaxIterations = 10
+
+// defaultModel is used when no model is configured
+const defaultModel = "qwen3-coder:30b"
+
+// New creates a coding agent configured by opts. Nothing is written to the
+// terminal unless an output writer is set with WithOutput.
+func New(client Provider, opts ...Option) *Agent {
+	return NewWithContext(context.Background(), client, opts...)
+}
+
+// NewWithContext is New with a context for fetching the model's details, so
+// that an unreachable backend can be given up on
+func NewWithContext(ctx context.Context, client Provider, opts ...Option) *Agent {
+	// Get current working directory
+	workDir, err := os.Getwd()
+	if err != nil {
+		workDir = "."
+	}
+
+	agent := &Agent{
+		client:              client,
+		systemPrompts:       make(map[string]string),
+		modelName:           defaultModel,
+		conversationHistory: make([]ollama.ChatMessage, 0),
+		actionParser:        NewActionParser(),
+		workDir:             workDir,
+		autoExecuteActions:  false, // Default to false for safety
+		maxIterations:       defaultMaxIterations,
+		apiModes:            make(map[string]APIMode),
+		compaction:          CompactionTruncate,
+		keepRecent:          defaultKeepRecent,
+		sessionDir:          DefaultSessionDir(),
+		journal:             NewJournal(),
+		commandOptions:      DefaultCommandOptions(),
+		out:                 io.Discard,
+	}
+	for _, opt := range opts {
+		opt(agent)
+	}
+
+	// Initialize model parameters
+	agent.loadModelInfo(ctx)
+
+	return agent
+}
+
+// NewAgent creates a coding agent for a model that writes to standard output.
+//
+// Deprecated: use New with WithModel and WithOutput.
+func NewAgent(ollamaClient *ollama.Client, modelName string) *Agent {
+	opts := []Option{WithOutput(os.Stdout)}
+	if modelName != "" {
+		opts = append(opts, WithModel(modelName))
+	}
+	return New(ollamaClient, opts...)
+}
+
+// loadModelInfo fetches details of the current model and updates the model
+// parameters and tool-calling support accordingly
+func (a *Agent) loadModelInfo(ctx context.Context) (*ollama.ShowModelResponse, error) {
+	info, err := a.client.ShowModelWithContext(ctx, a.modelName)
+	if err != nil {
+		return nil, err
+	}
+	if params, err := parseModelParameters(info.Parameters); err == nil {
+		a.modelParams = params
+	}
+	a.nativeTools = info.HasCapability("tools")
+	return info, nil
+}
+
+//go:embed prompts/*
+var promptsFS embed.FS
+
+// LoadSystemPromptDirectory loads all system prompts from a directory
+func (a *Agent) LoadSystemPromptDirectory(dirPath string) error {
+	entries, err := fs.ReadDir(promptsFS, dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to read prompt directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".txt" {
+			name := filepath.Base(entry.Name())
+			name = name[:len(name)-4] // Remove .txt extension
+			err := a.LoadSystemPrompt(name, "prompts/"+entry.Name())
+			if err != nil {
+				return fmt.Errorf("failed to load prompt %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// LoadSystemPrompt loads a system prompt from a file
+func (a *Agent) LoadSystemPrompt(name, filePath string) error {
+	data, err := fs.ReadFile(promptsFS, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read system prompt file: %w", err)
+	}
+
+	a.systemPrompts[name] = string(data)
+	return nil
+}
+
+// GetSystemPrompt returns a loaded system prompt by name
+func (a *Agent) GetSystemPrompt(name string) (string, bool) {
+	prompt, ok := a.systemPrompts[name]
+	return prompt, ok
+}
+
+// SetSystemPrompt sets the active system prompt for the agent
+func (a *Agent) SetSystemPrompt(prompt string) {
+	a.systemPrompt = prompt
+}
+
+// SetWorkDir sets the working directory for action execution
+func (a *Agent) SetWorkDir(dir string) {
+	a.workDir = dir
+}
+
+// SetReadRoots allows actions to read files under the given directories in
+// addition to the working directory. Writes are always confined to the
+// working directory.
+func (a *Agent) SetReadRoots(roots ...string) {
+	a.readRoots = roots
+}
+
+// SetCommandPolicy sets the policy every command is checked against before it
+// runs. Commands the policy asks about are confirmed on the terminal.
+func (a *Agent) SetCommandPolicy(policy *CommandPolicy) {
+	a.commandPolicy = policy
+}
+
+// SetCommandOptions sets how commands are run: shell mode, timeout and the
+// amount of output returned to the model
+func (a *Agent) SetCommandOptions(opts CommandOptions) {
+	a.commandOptions = opts
+}
+
+// SetReviewActions enables/disables interactive review, where each action is
+// shown (with a diff for file changes) and approved, skipped, edited or
+// aborted before it runs
+func (a *Agent) SetReviewActions(enabled bool) {
+	a.reviewActions = enabled
+}
+
+// SetApprover reviews every action with approve before it runs, instead of
+// asking on the terminal. Pass nil to remove it.
+func (a *Agent) SetApprover(approve ApproveFunc) {
+	a.approver = approve
+}
+
+// RegisterAction adds a custom action type that the agent parses from
+// responses, offers as a native tool and describes in the system prompt
+func (a *Agent) RegisterAction(def ActionDefinition) error {
+	return a.actionRegistry().Register(def)
+}
+
+// actionRegistry returns the registry of actions the agent understands
+func (a *Agent) actionRegistry() *ActionRegistry {
+	if a.actionParser == nil {
+		a.actionParser = NewActionParser()
+	}
+	return a.actionParser.Registry()
+}
+
+// SetEagerReads makes the agent read files as soon as a read action streams
+// in, before the model has finished its response. It only applies while
+// actions are executed automatically.
+func (a *Agent) SetEagerReads(enabled bool) {
+	a.eagerReads = enabled
+}
+
+// SetEventSink sends the agent's events to sink instead of rendering them on
+// the output writer
+func (a *Agent) SetEventSink(sink EventSink) {
+	a.events = sink
+}
+
+// eventSink returns the sink events go to; by default they are rendered as
+// text on the output writer
+func (a *Agent) eventSink() EventSink {
+	if a.events == nil {
+		a.events = NewTerminalRenderer(a.out)
+	}
+	return a.events
+}
+
+// emit reports an event to the agent's sink
+func (a *Agent) emit(ev Event) {
+	emit(a.eventSink(), ev)
+}
+
+// SetAutoExecuteActions enables/disables automatic action execution
+func (a *Agent) SetAutoExecuteActions(enabled bool) {
+	a.autoExecuteActions = enabled
+}
+
+// SetRollbackOnFailure makes the agent undo a whole batch of actions when any
+// action in it fails
+func (a *Agent) SetRollbackOnFailure(enabled bool) {
+	a.rollbackOnFailure = enabled
+}
+
+// Undo reverts the file changes made by the most recent batch of actions, or
+// by every batch when all is true, and tells the model about it. It returns
+// the number of paths restored. Commands that were run are not undone.
+func (a *Agent) Undo(all bool) (int, error) {
+	var restored int
+	var err error
+	if all {
+		restored, err = a.journal.UndoAll()
+	} else {
+		restored, err = a.journal.Undo()
+	}
+	if restored > 0 {
+		scope := "the last batch of actions"
+		if all {
+			scope = "all actions executed so far"
+		}
+		a.conversationHistory = append(a.conversationHistory, ollama.ChatMessage{
+			Role:    "tool",
+			Content: fmt.Sprintf("The user undid the file changes made by %s (%d path(s) restored).", scope, restored),
+		})
+	}
+	return restored, err
+}
+
+// SetMaxIterations sets how many model turns a single message may trigger
+// when action results are fed back automatically. Values below 1 reset it to
+// the default.
+func (a *Agent) SetMaxIterations(n int) {
+	if n < 1 {
+		n = defaultMaxIterations
+	}
+	a.maxIterations = n
+}
+
+// SetNativeTools enables/disables native tool calling. When enabled, actions
+// are offered to the model as tools over the chat API and tool calls are used
+// instead of parsing action tags. It is enabled automatically for models that
+// advertise the "tools" capability.
+func (a *Agent) SetNativeTools(enabled bool) {
+	a.nativeTools = enabled
+}
+
+// SetAPIMode selects the endpoint used for the given model. Models without an
+// explicit mode use the chat API.
+func (a *Agent) SetAPIMode(model string, mode APIMode) {
+	if mode == APIModeChat {
+		delete(a.apiModes, model)
+		return
+	}
+	a.apiModes[model] = mode
+}
+
+// apiMode returns the endpoint used for the current model. Providers without
+// a generate API always use chat.
+func (a *Agent) apiMode() APIMode {
+	if _, ok := a.client.(generator); !ok {
+		return APIModeChat
+	}
+	if mode, ok := a.apiModes[a.modelName]; ok {
+		return mode
+	}
+	return APIModeChat
+}
+
+// ClearHistory clears the conversation history and any pending actions
+func (a *Agent) ClearHistory() {
+	a.conversationHistory = make([]ollama.ChatMessage, 0)
+	a.pendingActions = nil
+	a.pendingFromTools = false
+	a.turnStats = nil
+}
+
+// SendMessage sends a message to the agent and streams the response. When
+// auto-execution is enabled, actions found in the response are executed and
+// their results are fed back to the model, which is re-invoked until it stops
+// emitting actions or the iteration budget is exhausted. onChunk may be nil;
+// streamed text is also reported to the event sink as EventToken.
+func (a *Agent) SendMessage(ctx context.Context, message string, onChunk func(string) error) error {
+	// Add user message to history
+	a.conversationHistory = append(a.conversationHistory, ollama.ChatMessage{
+		Role:    "user",
+		Content: message,
+	})
+
+	a.turnResults = nil
+	return a.runLoop(ctx, onChunk)
+}
+
+// ExecutePendingActions runs the actions detected in the last response,
+// feeds their results back to the model and continues the conversation.
+func (a *Agent) ExecutePendingActions(ctx context.Context, onChunk func(string) error) error {
+	if len(a.pendingActions) == 0 {
+		return nil
+	}
+
+	actions := a.pendingActions
+	a.pendingActions = nil
+	a.turnResults = nil
+	a.executeAndRecord(ctx, actions, a.pendingFromTools)
+
+	return a.runLoop(ctx, onChunk)
+}
+
+// runLoop invokes the model on the current history and keeps going for as long
+// as it produces actions that are executed automatically.
+func (a *Agent) runLoop(ctx context.Context, onChunk func(string) error) error {
+	for iteration := 1; ; iteration++ {
+		// Make room in the context window before each model call
+		a.maybeCompact(ctx)
+
+		a.emit(Event{Type: EventTurnStarted, Iteration: iteration, Model: a.modelName})
+
+		// Report action tags as soon as they stream in
+		stream := NewStreamParser(a.actionParser)
+		detect := func(chunk string) error {
+			a.emit(Event{Type: EventToken, Text: chunk})
+			if onChunk != nil {
+				if err := onChunk(chunk); err != nil {
+					return err
+				}
+			}
+			for _, action := range stream.Feed(chunk) {
+				a.actionDetected(ctx, action)
+			}
+			return nil
+		}
+
+		var actions []Action
+		var err error
+		if a.apiMode() == APIModeGenerate {
+			_, err = a.streamGenerate(ctx, detect)
+		} else {
+			_, actions, err = a.streamChat(ctx, detect)
+		}
+		if err != nil {
+			a.prefetched = nil
+			return err
+		}
+
+		// Tool calls take precedence; otherwise fall back to parsing action tags
+		fromTools := len(actions) > 0
+		if !fromTools {
+			stream.Flush()
+			actions = stream.Actions()
+		}
+		if len(actions) == 0 {
+			return nil
+		}
+
+		a.emit(Event{Type: EventActions, Actions: actions})
+
+		if !a.autoExecuteActions {
+			// Store as pending actions so the user can run /execute later
+			a.pendingActions = actions
+			a.pendingFromTools = fromTools
+			a.emit(Event{Type: EventInfo, Text: "💡 Tip: Use /execute to run these actions, /review to approve them one by one, or enable auto-execution with /auto on"})
+			return nil
+		}
+
+		a.emit(Event{Type: EventInfo, Text: "⚙️  Auto-executing actions..."})
+		a.executeAndRecord(ctx, actions, fromTools)
+
+		if iteration >= a.maxIterations {
+			a.emit(Event{Type: EventInfo, Text: fmt.Sprintf("⚠️  Reached the limit of %d iteration(s); action results were recorded but the model was not re-invoked", a.maxIterations)})
+			return nil
+		}
+
+		a.emit(Event{Type: EventInfo, Text: "🔁 Sending action results back to the model..."})
+	}
+}
+
+// actionDetected reports an action while the response is still streaming and,
+// with eager reads enabled, runs it right away if it is safe to do so. Actions
+// that will be reviewed are not run early, so a skipped read stays unread.
+func (a *Agent) actionDetected(ctx context.Context, action Action) {
+	a.emit(Event{Type: EventActionDetected, Action: action})
+
+	if !a.eagerReads || !a.autoExecuteActions || a.reviewActions || a.approver != nil {
+		return
+	}
+	if !isSafeAction(action) || action.Validate() != nil {
+		return
+	}
+	sb, err := NewSandbox(a.workDir, a.readRoots...)
+	if err != nil {
+		return
+	}
+	if a.prefetched == nil {
+		a.prefetched = make(map[Action]error)
+	}
+	a.prefetched[action] = action.Execute(withOutput(withSandbox(ctx, sb), a.out), a.workDir)
+}
+
+// executeAndRecord executes actions and appends their results to the
+// conversation history. Results of native tool calls are recorded as one tool
+// message per call; results of parsed action tags share a single message.
+func (a *Agent) executeAndRecord(ctx context.Context, actions []Action, fromTools bool) {
+	executor := NewExecutor(a.workDir)
+	executor.SetReadRoots(a.readRoots...)
+	executor.SetJournal(a.journal)
+	executor.SetRollbackOnFailure(a.rollbackOnFailure)
+	executor.SetCommandOptions(a.commandOptions)
+	executor.SetOutput(a.out)
+	executor.SetEventSink(a.eventSink())
+	if a.approver != nil {
+		executor.SetApprover(a.approver)
+	} else if a.reviewActions {
+		executor.SetApprover(a.reviewAction)
+	}
+	if a.commandPolicy != nil {
+		executor.SetCommandPolicy(a.commandPolicy, a.askCommand)
+	}
+	executor.SetPrefetched(a.prefetched)
+	a.prefetched = nil
+	results := executor.run(ctx, actions)
+	a.lastResults = results
+	a.turnResults = append(a.turnResults, results...)
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		a.emit(Event{Type: EventInfo, Text: fmt.Sprintf("⚠️  Completed with %d failure(s)", failed)})
+	} else {
+		a.emit(Event{Type: EventInfo, Text: "✅ All actions completed successfully"})
+	}
+
+	if fromTools {
+		for i, r := range results {
+			a.conversationHistory = append(a.conversationHistory, ollama.ChatMessage{
+				Role:     "tool",
+				ToolName: toolName(r.Action),
+				Content:  FormatResults(results[i : i+1]),
+			})
+		}
+		return
+	}
+
+	a.conversationHistory = append(a.conversationHistory, ollama.ChatMessage{
+		Role:    "tool",
+		Content: FormatResults(results),
+	})
+}
+
+// LastResults returns the results of the most recently executed batch of
+// actions
+func (a *Agent) LastResults() []ActionResult {
+	return a.lastResults
+}
+
+// TurnResults returns the results of every action executed since the last
+// call to SendMessage or ExecutePendingActions, across all of its iterations
+func (a *Agent) TurnResults() []ActionResult {
+	return a.turnResults
+}
+
+// PendingActions returns the actions detected in the last response that are
+// waiting for ExecutePendingActions
+func (a *Agent) PendingActions() []Action {
+	return a.pendingActions
+}
+
+// DiscardPendingActions drops the pending actions without running them and
+// returns how many there were
+func (a *Agent) DiscardPendingActions() int {
+	n := len(a.pendingActions)
+	a.pendingActions = nil
+	a.pendingFromTools = false
+	return n
+}
+
+// History returns a copy of the conversation history
+func (a *Agent) History() []ollama.ChatMessage {
+	return append([]ollama.ChatMessage(nil), a.conversationHistory...)
+}
+
+// WorkDir returns the directory actions run in
+func (a *Agent) WorkDir() string {
+	return a.workDir
+}
+
+// systemMessage returns the system prompt followed by a description of the
+// registered actions the prompt files don't cover
+func (a *Agent) systemMessage() string {
+	systemPrompt := a.systemPrompt
+	if section := a.actionRegistry().promptSection(); section != "" {
+		systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + section)
+	}
+	return systemPrompt
+}
+
+// buildMessages returns the conversation history prefixed with the system
+// prompt, if one is set.
+func (a *Agent) buildMessages() []ollama.ChatMessage {
+	messages := make([]ollama.ChatMessage, 0, len(a.conversationHistory)+1)
+	if systemPrompt := a.systemMessage(); systemPrompt != "" {
+		messages = append(messages, ollama.ChatMessage{
+			Role:    "system",
+			Content: systemPrompt,
+		})
+	}
+	return append(messages, a.conversationHistory...)
+}
+
+// streamChat sends the conversation to the chat API, streams the text of the
+// reply through onChunk and records the reply in the history. With native
+// tools enabled every action is offered as a tool, and any actions requested
+// as tool calls are returned.
+func (a *Agent) streamChat(ctx context.Context, onChunk func(string) error) (string, []Action, error) {
+	messages := a.buildMessages()
+	req := &ollama.ChatRequest{
+		Model:    a.modelName,
+		Messages: messages,
+		Stream:   true,
+	}
+	if a.nativeTools {
+		req.Tools = a.actionRegistry().Tools()
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := a.client.ChatEvents(streamCtx, req)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to stream chat: %w", err)
+	}
+	response, toolCalls, stats, err := consumeEvents(streamCtx, events, onChunk)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to stream chat: %w", err)
+	}
+
+	a.recordTurn(len(messages), len(response), stats)
+
+	// Add assistant response, including any tool calls, to history
+	a.conversationHistory = append(a.conversationHistory, ollama.ChatMessage{
+		Role:      "assistant",
+		Content:   response,
+		ToolCalls: toolCalls,
+	})
+
+	actions := make([]Action, 0, len(toolCalls))
+	for _, call := range toolCalls {
+		actions = append(actions, a.actionRegistry().FromToolCall(call))
+	}
+
+	return response, actions, nil
+}
+
+// streamGenerate is the generate API fallback: it flattens the conversation
+// into a single prompt, streams the reply through onChunk and records it in
+// the history.
+func (a *Agent) streamGenerate(ctx context.Context, onChunk func(string) error) (string, error) {
+	// Build messages array with system prompt if set (for the stats only)
+	messages := a.buildMessages()
+
+	// Create generate request by flattening the conversation history into
+	// a single prompt. The system prompt is supplied separately in the
+	// GenerateRequest.System field so it is not repeated in the prompt.
+	var promptBuilder strings.Builder
+	for i, m := range a.conversationHistory {
+		if i > 0 {
+			promptBuilder.WriteString("\n\n")
+		}
+		role := strings.Title(m.Role)
+		promptBuilder.WriteString(role)
+		promptBuilder.WriteString(": ")
+		promptBuilder.WriteString(m.Content)
+	}
+
+	req := &ollama.GenerateRequest{
+		Model:  a.modelName,
+		System: a.systemMessage(),
+		Prompt: promptBuilder.String(),
+		Stream: true,
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := a.client.(generator).GenerateEvents(streamCtx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to stream generation: %w", err)
+	}
+	response, _, stats, err := consumeEvents(streamCtx, events, onChunk)
+	if err != nil {
+		return "", fmt.Errorf("failed to stream generation: %w", err)
+	}
+
+	a.recordTurn(len(messages), len(response), stats)
+
+	// Add assistant response to history
+	a.conversationHistory = append(a.conversationHistory, ollama.ChatMessage{
+		Role:    "assistant",
+		Content: response,
+	})
+
+	return response, nil
+}
+
+// consumeEvents drains a stream, forwarding response text to onChunk, and
+// returns the full text, any tool calls and the final statistics. The caller
+// must cancel ctx when done so an abandoned stream is released.
+func consumeEvents(ctx context.Context, events <-chan ollama.StreamEvent, onChunk func(string) error) (string, []ollama.ToolCall, *ollama.StreamStats, error) {
+	var fullResponse strings.Builder
+	var toolCalls []ollama.ToolCall
+
+	for ev := range events {
+		switch ev.Type {
+		case ollama.EventToken:
+			fullResponse.WriteString(ev.Text)
+			if err := onChunk(ev.Text); err != nil {
+				return "", nil, nil, err
+			}
+		case ollama.EventToolCall:
+			toolCalls = append(toolCalls, *ev.ToolCall)
+		case ollama.EventDone:
+			return fullResponse.String(), toolCalls, ev.Stats, nil
+		case ollama.EventError:
+			return "", nil, nil, ev.Err
+		}
+	}
+
+	// The channel was closed without a final event, so the stream was cancelled
+	if err := ctx.Err(); err != nil {
+		return "", nil, nil, err
+	}
+	return "", nil, nil, fmt.Errorf("stream ended unexpectedly")
+}
+
+// recordTurn keeps and reports the statistics of a model response
+func (a *Agent) recordTurn(messageCount, responseLength int, stats *ollama.StreamStats) {
+	a.lastResponseStats = stats
+	a.turnStats = append(a.turnStats, TurnStats{
+		Model:           a.modelName,
+		Time:            time.Now(),
+		PromptTokens:    stats.PromptEvalCount,
+		ResponseTokens:  stats.EvalCount,
+		TotalDuration:   stats.TotalDuration,
+		ResponseLength:  responseLength,
+		ContextMessages: messageCount,
+	})
+
+	ev := Event{
+		Type:           EventStats,
+		Model:          a.modelName,
+		Stats:          stats,
+		Messages:       messageCount,
+		ResponseLength: responseLength,
+	}
+	if a.modelParams != nil {
+		ev.ContextLength = a.modelParams.ContextLength
+	}
+	a.emit(ev)
+}
+
+// stdin returns the reader shared by the REPL and confirmation prompts
+func (a *Agent) stdin() *bufio.Reader {
+	if a.input == nil {
+		a.input = bufio.NewReader(os.Stdin)
+	}
+	return a.input
+}
+
+// askCommand asks the user on the terminal whether a command may run
+func (a *Agent) askCommand(command, reason string) CommandApproval {
+	fmt.Fprintf(a.out, "\n⚠️  The command policy requires approval to run:\n  %s\n", command)
+	if reason != "" {
+		fmt.Fprintf(a.out, "  Reason: %s\n", reason)
+	}
+	fmt.Fprint(a.out, "Run it? [y]es / [a]lways this session / [N]o: ")
+
+	answer, err := a.stdin().ReadString('\n')
+	if err != nil {
+		return ApprovalDeny
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return ApprovalOnce
+	case "a", "always":
+		return ApprovalSession
+	default:
+		return ApprovalDeny
+	}
+}
+
+// RunREPL starts an interactive REPL session with the agent
+func (a *Agent) RunREPL(ctx context.Context) error {
+	reader := a.stdin()
+
+	// Set up signal handling for Ctrl+C
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer signal.Stop(sigChan)
+
+	var currentCancel context.CancelFunc
+	var interrupted bool
+
+	// Handle Ctrl+C in a separate goroutine
+	go func() {
+		for range sigChan {
+			interrupted = true
+			if currentCancel != nil {
+				currentCancel()
+				fmt.Fprintln(a.out, "\n🛑 Interrupted! Stream stopped.")
+			} else {
+				fmt.Fprint(a.out, "\n> ")
+			}
+		}
+	}()
+
+	fmt.Fprintln(a.out, "╔════════════════════════════════════════════════════════════╗")
+	fmt.Fprintln(a.out, "║          Coding Agent REPL - Powered by Ollama            ║")
+	fmt.Fprintln(a.out, "╚════════════════════════════════════════════════════════════╝")
+	fmt.Fprintf(a.out, "Model: %s\n", a.modelName)
+	fmt.Fprintln(a.out, "\nCommands:")
+	fmt.Fprintln(a.out, "  /help         - Show this help message")
+	fmt.Fprintln(a.out, "  /clear        - Clear conversation history")
+	fmt.Fprintln(a.out, "  /model <name> - Switch to a different model")
+	fmt.Fprintln(a.out, "  /pull <name>  - Download a model (Ollama only)")
+	fmt.Fprintln(a.out, "  /system <msg> - Set system prompt")
+	fmt.Fprintln(a.out, "  /prompt <name>- Load a saved system prompt")
+	fmt.Fprintln(a.out, "  /workdir <dir>- Set working directory for actions")
+	fmt.Fprintln(a.out, "  /auto <on|off>- Enable/disable auto-execution of actions")
+	fmt.Fprintln(a.out, "  /execute      - Run pending actions and continue the conversation")
+	fmt.Fprintln(a.out, "  /review [on|off]- Review pending actions one by one, or always review")
+	fmt.Fprintln(a.out, "  /undo [all]   - Revert file changes of the last (or every) batch")
+	fmt.Fprintln(a.out, "  /rollback <on|off>- Roll back a batch automatically when an action fails")
+	fmt.Fprintln(a.out, "  /tools <on|off>- Enable/disable native tool calling")
+	fmt.Fprintln(a.out, "  /api <mode>   - Use the chat or generate API for this model")
+	fmt.Fprintln(a.out, "  /compact <s>  - Set history compaction (none|truncate|summarize) or run it now")
+	fmt.Fprintln(a.out, "  /save <name>  - Save the conversation as a session")
+	fmt.Fprintln(a.out, "  /load <name>  - Resume a saved session")
+	fmt.Fprintln(a.out, "  /sessions     - List saved sessions")
+	fmt.Fprintln(a.out, "  /policy       - Show the command policy and session approvals")
+	fmt.Fprintln(a.out, "  /shell <on|off>- Run commands through /bin/sh (pipes, redirects, &&)")
+	fmt.Fprintln(a.out, "  /eager <on|off>- Read files while the response is still streaming")
+	fmt.Fprintln(a.out, "  /results [json]- Show the results of the last batch of actions")
+	fmt.Fprintln(a.out, "  /exit or /quit- Exit the REPL")
+	fmt.Fprintln(a.out, "\nType your message and press Enter to chat.")
+	fmt.Fprintln(a.out)
+
+	for {
+		if !interrupted {
+			fmt.Fprint(a.out, "\n> ")
+		}
+		interrupted = false
+
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			if err.Error() == "interrupt" {
+				continue
+			}
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		// Create a new cancellable context for this interaction
+		streamCtx, cancel := context.WithCancel(ctx)
+		currentCancel = cancel
+		defer func() {
+			currentCancel = nil
+			cancel()
+		}()
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+
+		// Handle commands
+		if strings.HasPrefix(input, "/") {
+			if err := a.handleCommand(streamCtx, input); err != nil {
+				if err.Error() == "exit" {
+					fmt.Fprintln(a.out, "\nGoodbye!")
+					return nil
+				}
+				fmt.Fprintf(a.out, "Error: %v\n", err)
+			}
+			continue
+		}
+
+		// Send message and stream response
+		fmt.Fprintln(a.out)
+		err = a.SendMessage(streamCtx, input, a.printChunk)
+		if err != nil {
+			if err == context.Canceled || strings.Contains(err.Error(), "context canceled") {
+				fmt.Fprint(a.out, "\n💡 Tip: The response was interrupted. Continue with your next question!\n\n> ")
+				continue
+			}
+			if errors.Is(err, ollama.ErrServerUnavailable) {
+				fmt.Fprintf(a.out, "\n✖ The model server is unavailable: %v\n💡 Tip: Check that it is running.\n\n> ", err)
+				continue
+			}
+			fmt.Fprintf(a.out, "\nError: %v\n\n> ", err)
+			continue
+		}
+		fmt.Fprintln(a.out)
+	}
+}
+
+// printChunk writes streamed response text to the output
+func (a *Agent) printChunk(chunk string) error {
+	fmt.Fprint(a.out, chunk)
+	return nil
+}
+
+// handleCommand processes REPL commands
+func (a *Agent) handleCommand(ctx context.Context, cmd string) error {
+	parts := strings.Fields(cmd)
+	if len(parts) == 0 {
+		return nil
+	}
+
+	switch parts[0] {
+	case "/help":
+		fmt.Fprintln(a.out, "\nAvailable Commands:")
+		fmt.Fprintln(a.out, "  /help         - Show this help message")
+		fmt.Fprintln(a.out, "  /clear        - Clear conversation history")
+		fmt.Fprintln(a.out, "  /model <name> - Switch to a different model")
+		fmt.Fprintln(a.out, "  /pull <name>  - Download a model (Ollama only)")
+		fmt.Fprintln(a.out, "  /system <msg> - Set system prompt")
+		fmt.Fprintln(a.out, "  /prompt <name>- Load a saved system prompt")
+		fmt.Fprintln(a.out, "  /workdir <dir>- Set working directory for actions")
+		fmt.Fprintln(a.out, "  /auto <on|off>- Enable/disable auto-execution of actions")
+		fmt.Fprintln(a.out, "  /execute      - Run pending actions and continue the conversation")
+		fmt.Fprintln(a.out, "  /review [on|off]- Review pending actions one by one, or always review")
+		fmt.Fprintln(a.out, "  /undo [all]   - Revert file changes of the last (or every) batch")
+		fmt.Fprintln(a.out, "  /rollback <on|off>- Roll back a batch automatically when an action fails")
+		fmt.Fprintln(a.out, "  /tools <on|off>- Enable/disable native tool calling")
+		fmt.Fprintln(a.out, "  /api <mode>   - Use the chat or generate API for this model")
+		fmt.Fprintln(a.out, "  /compact <s>  - Set history compaction (none|truncate|summarize) or run it now")
+		fmt.Fprintln(a.out, "  /save <name>  - Save the conversation as a session")
+		fmt.Fprintln(a.out, "  /load <name>  - Resume a saved session")
+		fmt.Fprintln(a.out, "  /sessions     - List saved sessions")
+		fmt.Fprintln(a.out, "  /policy       - Show the command policy and session approvals")
+		fmt.Fprintln(a.out, "  /shell <on|off>- Run commands through /bin/sh (pipes, redirects, &&)")
+		fmt.Fprintln(a.out, "  /eager <on|off>- Read files while the response is still streaming")
+		fmt.Fprintln(a.out, "  /results [json]- Show the results of the last batch of actions")
+		fmt.Fprintln(a.out, "  /exit, /quit  - Exit the REPL")
+
+	case "/clear":
+		a.ClearHistory()
+		fmt.Fprintln(a.out, "✓ Conversation history cleared")
+
+	case "/model":
+		if len(parts) < 2 {
+			fmt.Fprintf(a.out, "Current model: %s\n", a.modelName)
+			fmt.Fprintln(a.out, "Usage: /model <model-name>")
+		} else {
+			a.modelName = parts[1]
+			// Get model parameters and details
+			if info, err := a.loadModelInfo(ctx); err == nil {
+				fmt.Fprintf(a.out, "\n🤖 Model Information:\n")
+				fmt.Fprintf(a.out, "  • Name: %s\n", a.modelName)
+				if info.License != "" {
+					fmt.Fprintf(a.out, "  • License: %s\n", info.License)
+				}
+				if info.Details.Format != "" {
+					fmt.Fprintf(a.out, "  • Format: %s\n", info.Details.Format)
+				}
+				if info.Details.Family != "" {
+					fmt.Fprintf(a.out, "  • Family: %s\n", info.Details.Family)
+				}
+				if info.Details.ParameterSize != "" {
+					fmt.Fprintf(a.out, "  • Size: %s\n", info.Details.ParameterSize)
+				}
+				if info.Details.QuantizationLevel != "" {
+					fmt.Fprintf(a.out, "  • Quantization: %s\n", info.Details.QuantizationLevel)
+				}
+				if len(info.Capabilities) > 0 {
+					fmt.Fprintf(a.out, "  • Capabilities: %s\n", strings.Join(info.Capabilities, ", "))
+				}
+
+				if a.modelParams != nil {
+					fmt.Fprintf(a.out, "\n⚙️ Model Parameters:\n")
+					if a.modelParams.ContextLength > 0 {
+						fmt.Fprintf(a.out, "  • Context Window: %d tokens\n", a.modelParams.ContextLength)
+					}
+					if a.modelParams.EmbeddingLength > 0 {
+						fmt.Fprintf(a.out, "  • Embedding Size: %d\n", a.modelParams.EmbeddingLength)
+					}
+					if a.modelParams.GPULayers > 0 {
+						fmt.Fprintf(a.out, "  • GPU Layers: %d\n", a.modelParams.GPULayers)
+					}
+					if a.modelParams.Template != "" {
+						fmt.Fprintf(a.out, "  • Template: %s\n", a.modelParams.Template)
+					}
+				}
+				fmt.Fprintf(a.out, "\n✓ Successfully switched to model\n")
+			} else {
+				fmt.Fprintf(a.out, "✓ Switched to model: %s (could not fetch details: %v)\n", a.modelName, err)
+			}
+		}
+
+	case "/pull":
+		if len(parts) < 2 {
+			fmt.Fprintln(a.out, "Usage: /pull <model-name>")
+		} else {
+			bar := NewProgressBar(a.out)
+			err := a.PullModel(ctx, parts[1], bar.Update)
+			bar.Finish()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(a.out, "✓ Pulled %s (switch to it with /model %s)\n", parts[1], parts[1])
+		}
+
+	case "/system":
+		if len(parts) < 2 {
+			if a.systemPrompt == "" {
+				fmt.Fprintln(a.out, "No system prompt set")
+			} else {
+				fmt.Fprintf(a.out, "Current system prompt:\n%s\n", a.systemPrompt)
+			}
+			fmt.Fprintln(a.out, "Usage: /system <name|message>  (if <name> matches a loaded prompt it will be used)")
+		} else {
+			// If the argument matches a loaded prompt name, use that prompt.
+			nameOrMsg := strings.Join(parts[1:], " ")
+			if prompt, ok := a.systemPrompts[nameOrMsg]; ok {
+				a.systemPrompt = prompt
+				fmt.Fprintf(a.out, "✓ Loaded system prompt: %s\n", nameOrMsg)
+			} else {
+				// No matching prompt name — treat the argument as the inline system message.
+				a.systemPrompt = nameOrMsg
+				fmt.Fprintln(a.out, "✓ System prompt updated")
+			}
+		}
+
+	case "/prompt":
+		if len(parts) < 2 {
+			fmt.Fprintln(a.out, "Available prompts:")
+			for name := range a.systemPrompts {
+				fmt.Fprintf(a.out, "  - %s\n", name)
+			}
+			fmt.Fprintln(a.out, "Usage: /prompt <name>")
+		} else {
+			prompt, ok := a.GetSystemPrompt(parts[1])
+			if !ok {
+				return fmt.Errorf("prompt '%s' not found", parts[1])
+			}
+			a.systemPrompt = prompt
+			fmt.Fprintf(a.out, "✓ Loaded system prompt: %s\n", parts[1])
+		}
+
+	case "/workdir":
+		if len(parts) < 2 {
+			fmt.Fprintf(a.out, "Current working directory: %s\n", a.workDir)
+			fmt.Fprintln(a.out, "Usage: /workdir <directory>")
+		} else {
+			newDir := strings.Join(parts[1:], " ")
+			// Expand ~ to home directory
+			if strings.HasPrefix(newDir, "~") {
+				home, err := os.UserHomeDir()
+				if err == nil {
+					newDir = filepath.Join(home, newDir[1:])
+				}
+			}
+
+			// Check if directory exists
+			if info, err := os.Stat(newDir); err != nil || !info.IsDir() {
+				return fmt.Errorf("directory does not exist: %s", newDir)
+			}
+
+			a.workDir = newDir
+			fmt.Fprintf(a.out, "✓ Working directory set to: %s\n", a.workDir)
+		}
+
+	case "/auto":
+		if len(parts) < 2 {
+			status := "disabled"
+			if a.autoExecuteActions {
+				status = "enabled"
+			}
+			fmt.Fprintf(a.out, "Auto-execution is currently: %s\n", status)
+			fmt.Fprintln(a.out, "Usage: /auto <on|off>")
+		} else {
+			switch strings.ToLower(parts[1]) {
+			case "on", "true", "1", "yes":
+				a.autoExecuteActions = true
+				fmt.Fprintln(a.out, "✓ Auto-execution enabled")
+			case "off", "false", "0", "no":
+				a.autoExecuteActions = false
+				fmt.Fprintln(a.out, "✓ Auto-execution disabled")
+			default:
+				return fmt.Errorf("invalid value: %s (use 'on' or 'off')", parts[1])
+			}
+		}
+
+	case "/tools":
+		if len(parts) < 2 {
+			status := "disabled"
+			if a.nativeTools {
+				status = "enabled"
+			}
+			fmt.Fprintf(a.out, "Native tool calling is currently: %s\n", status)
+			fmt.Fprintln(a.out, "Usage: /tools <on|off>")
+		} else {
+			switch strings.ToLower(parts[1]) {
+			case "on", "true", "1", "yes":
+				a.nativeTools = true
+				fmt.Fprintln(a.out, "✓ Native tool calling enabled")
+			case "off", "false", "0", "no":
+				a.nativeTools = false
+				fmt.Fprintln(a.out, "✓ Native tool calling disabled (using action tags)")
+			default:
+				return fmt.Errorf("invalid value: %s (use 'on' or 'off')", parts[1])
+			}
+		}
+
+	case "/api":
+		if len(parts) < 2 {
+			fmt.Fprintf(a.out, "API for %s is currently: %s\n", a.modelName, a.apiMode())
+			fmt.Fprintln(a.out, "Usage: /api <chat|generate>")
+		} else {
+			switch mode := APIMode(strings.ToLower(parts[1])); mode {
+			case APIModeChat, APIModeGenerate:
+				if _, ok := a.client.(generator); !ok && mode == APIModeGenerate {
+					return fmt.Errorf("the generate API is not available with this provider")
+				}
+				a.SetAPIMode(a.modelName, mode)
+				fmt.Fprintf(a.out, "✓ Using the %s API for %s\n", mode, a.modelName)
+			default:
+				return fmt.Errorf("invalid value: %s (use 'chat' or 'generate')", parts[1])
+			}
+		}
+
+	case "/compact":
+		if len(parts) < 2 {
+			fmt.Fprintf(a.out, "Compaction strategy: %s (keeping the last %d exchange(s))\n", a.compaction, a.keepRecent)
+			if window := a.contextWindow(); window > 0 {
+				fmt.Fprintf(a.out, "Estimated prompt size: ~%d/%d tokens\n", a.promptTokens(), window)
+			} else {
+				fmt.Fprintf(a.out, "Estimated prompt size: ~%d tokens (context window unknown)\n", a.promptTokens())
+			}
+			fmt.Fprintln(a.out, "Usage: /compact <none|truncate|summarize|now>")
+		} else {
+			switch strategy := CompactionStrategy(strings.ToLower(parts[1])); strategy {
+			case CompactionNone, CompactionTruncate, CompactionSummarize:
+				a.compaction = strategy
+				fmt.Fprintf(a.out, "✓ Compaction strategy set to: %s\n", strategy)
+			case "now":
+				if a.compaction == CompactionNone {
+					return fmt.Errorf("compaction is disabled (use /compact truncate or /compact summarize)")
+				}
+				return a.compactHistory(ctx, 0)
+			default:
+				return fmt.Errorf("invalid value: %s (use none, truncate, summarize or now)", parts[1])
+			}
+		}
+
+	case "/save":
+		if len(parts) < 2 {
+			fmt.Fprintln(a.out, "Usage: /save <name>")
+		} else {
+			path, err := a.SaveSession(parts[1])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(a.out, "✓ Session saved to: %s\n", path)
+		}
+
+	case "/load":
+		if len(parts) < 2 {
+			fmt.Fprintln(a.out, "Usage: /load <name>  (use /sessions to list saved sessions)")
+		} else {
+			if err := a.LoadSession(ctx, parts[1]); err != nil {
+				return err
+			}
+			fmt.Fprintf(a.out, "✓ Loaded session %s (%s, %d message(s)", parts[1], a.modelName, len(a.conversationHistory))
+			if len(a.pendingActions) > 0 {
+				fmt.Fprintf(a.out, ", %d pending action(s)", len(a.pendingActions))
+			}
+			fmt.Fprintln(a.out, ")")
+		}
+
+	case "/sessions":
+		sessions, err := a.ListSessions()
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			fmt.Fprintf(a.out, "No saved sessions in %s\n", a.sessionDir)
+			return nil
+		}
+		fmt.Fprintln(a.out, "Saved sessions:")
+		for _, s := range sessions {
+			fmt.Fprintf(a.out, "  - %s (%s, %d message(s), saved %s)\n",
+				s.Name, s.Model, s.Messages, s.SavedAt.Format("2006-01-02 15:04"))
+		}
+
+	case "/undo":
+		all := len(parts) > 1 && strings.ToLower(parts[1]) == "all"
+		if len(parts) > 1 && !all {
+			return fmt.Errorf("invalid value: %s (use /undo or /undo all)", parts[1])
+		}
+		restored, err := a.Undo(all)
+		if restored > 0 {
+			fmt.Fprintf(a.out, "↩️  Restored %d path(s)\n", restored)
+		}
+		if err != nil {
+			return err
+		}
+
+	case "/rollback":
+		if len(parts) < 2 {
+			status := "disabled"
+			if a.rollbackOnFailure {
+				status = "enabled"
+			}
+			fmt.Fprintf(a.out, "Automatic rollback of failed batches is currently: %s\n", status)
+			fmt.Fprintln(a.out, "Usage: /rollback <on|off>")
+		} else {
+			switch strings.ToLower(parts[1]) {
+			case "on", "true", "1", "yes":
+				a.rollbackOnFailure = true
+				fmt.Fprintln(a.out, "✓ Failed batches will be rolled back")
+			case "off", "false", "0", "no":
+				a.rollbackOnFailure = false
+				fmt.Fprintln(a.out, "✓ Automatic rollback disabled")
+			default:
+				return fmt.Errorf("invalid value: %s (use 'on' or 'off')", parts[1])
+			}
+		}
+
+	case "/review":
+		if len(parts) < 2 {
+			if len(a.pendingActions) == 0 {
+				status := "disabled"
+				if a.reviewActions {
+					status = "enabled"
+				}
+				fmt.Fprintf(a.out, "No pending actions to review. Review before every action is currently: %s\n", status)
+				fmt.Fprintln(a.out, "Usage: /review (review pending actions) or /review <on|off>")
+				return nil
+			}
+			fmt.Fprintln(a.out, "\n🔍 Reviewing pending actions...")
+			// Review applies to this batch and anything the model asks for
+			// while continuing the conversation
+			previous := a.reviewActions
+			a.reviewActions = true
+			err := a.ExecutePendingActions(ctx, a.printChunk)
+			a.reviewActions = previous
+			if err != nil {
+				return fmt.Errorf("execution failed: %w", err)
+			}
+			fmt.Fprintln(a.out)
+		} else {
+			switch strings.ToLower(parts[1]) {
+			case "on", "true", "1", "yes":
+				a.reviewActions = true
+				fmt.Fprintln(a.out, "✓ Each action will be shown for approval before it runs")
+			case "off", "false", "0", "no":
+				a.reviewActions = false
+				fmt.Fprintln(a.out, "✓ Action review disabled")
+			default:
+				return fmt.Errorf("invalid value: %s (use 'on' or 'off')", parts[1])
+			}
+		}
+
+	case "/shell":
+		if len(parts) < 2 {
+			status := "disabled"
+			if a.commandOptions.Shell {
+				status = "enabled"
+			}
+			fmt.Fprintf(a.out, "Shell mode is currently: %s\n", status)
+			fmt.Fprintln(a.out, "Usage: /shell <on|off>")
+		} else {
+			switch strings.ToLower(parts[1]) {
+			case "on", "true", "1", "yes":
+				a.commandOptions.Shell = true
+				fmt.Fprintln(a.out, "✓ Commands will run with /bin/sh -c")
+			case "off", "false", "0", "no":
+				a.commandOptions.Shell = false
+				fmt.Fprintln(a.out, "✓ Commands will run directly, without a shell")
+			default:
+				return fmt.Errorf("invalid value: %s (use 'on' or 'off')", parts[1])
+			}
+		}
+
+	case "/eager":
+		if len(parts) < 2 {
+			status := "disabled"
+			if a.eagerReads {
+				status = "enabled"
+			}
+			fmt.Fprintf(a.out, "Eager reads are currently: %s\n", status)
+			fmt.Fprintln(a.out, "Usage: /eager <on|off>")
+		} else {
+			switch strings.ToLower(parts[1]) {
+			case "on", "true", "1", "yes":
+				a.eagerReads = true
+				fmt.Fprintln(a.out, "✓ Files will be read as soon as a read action streams in (with /auto on)")
+			case "off", "false", "0", "no":
+				a.eagerReads = false
+				fmt.Fprintln(a.out, "✓ Files will be read when the response is complete")
+			default:
+				return fmt.Errorf("invalid value: %s (use 'on' or 'off')", parts[1])
+			}
+		}
+
+	case "/results":
+		if len(a.lastResults) == 0 {
+			fmt.Fprintln(a.out, "No actions have been executed yet")
+			return nil
+		}
+		if len(parts) > 1 && strings.ToLower(parts[1]) == "json" {
+			data, err := json.MarshalIndent(a.lastResults, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to encode results: %w", err)
+			}
+			fmt.Fprintln(a.out, string(data))
+			return nil
+		}
+		fmt.Fprintln(a.out, FormatResults(a.lastResults))
+
+	case "/policy":
+		if a.commandPolicy == nil {
+			fmt.Fprintln(a.out, "No command policy loaded; all commands run without asking")
+			return nil
+		}
+		fmt.Fprintf(a.out, "Default decision: %s\n", a.commandPolicy.Default())
+		rules := a.commandPolicy.Rules()
+		if len(rules) > 0 {
+			fmt.Fprintln(a.out, "Rules:")
+		}
+		for i, rule := range rules {
+			binary, args := rule.Binary, rule.Args
+			if binary == "" {
+				binary = "*"
+			}
+			if args == "" {
+				args = ".*"
+			}
+			fmt.Fprintf(a.out, "  %d. %s %s → %s", i+1, binary, args, rule.Decision)
+			if rule.Reason != "" {
+				fmt.Fprintf(a.out, " (%s)", rule.Reason)
+			}
+			fmt.Fprintln(a.out)
+		}
+		if approved := a.commandPolicy.Approved(); len(approved) > 0 {
+			fmt.Fprintln(a.out, "Approved for this session:")
+			for _, command := range approved {
+				fmt.Fprintf(a.out, "  • %s\n", command)
+			}
+		}
+
+	case "/exit", "/quit":
+		return fmt.Errorf("exit")
+
+	case "/execute":
+		if len(a.pendingActions) == 0 {
+			fmt.Fprintln(a.out, "No pending actions to execute")
+			return nil
+		}
+		fmt.Fprintln(a.out, "\n⚙️  Executing pending actions...")
+		// Results are fed back to the model, which continues the conversation
+		if err := a.ExecutePendingActions(ctx, a.printChunk); err != nil {
+			return fmt.Errorf("execution failed: %w", err)
+		}
+		fmt.Fprintln(a.out)
+
+	default:
+		return fmt.Errorf("unknown command: %s (type /help for available commands)", parts[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+var (
+	createFileBodyRegex = regexp.MustCompile(`(?s)<path>(.*?)</path>\s*<content>(.*)</content>`)
+	modifyFileBodyRegex = regexp.MustCompile(`(?s)<path>(.*?)</path>\s*<search>(.*?)</search>\s*<replace>(.*?)</replace>(.*)`)
+	modifyOptionRegex   = regexp.MustCompile(`(?s)<(match|occurrence)>(.*?)</(?:match|occurrence)>`)
+	patchChildRegex     = regexp.MustCompile(`(?s)^\s*(?:<path>(.*?)</path>\s*)?<patch>(.*?)</patch>\s*$`)
+)
+
+// builtinActions defines the actions every agent understands
+func builtinActions() []ActionDefinition {
+	return []ActionDefinition{
+		{
+			Name:        "create_file",
+			Description: "Create a file (or overwrite an existing one) with the given content",
+			Required:    []string{"path", "content"},
+			Parameters: map[string]ollama.ToolProperty{
+				"path":    {Type: "string", Description: "File path relative to the working directory"},
+				"content": {Type: "string", Description: "Complete file content"},
+			},
+			ParseXML: func(body string) Action {
+				match := createFileBodyRegex.FindStringSubmatch(body)
+				if match == nil {
+					return nil
+				}
+				return &CreateFileAction{Path: strings.TrimSpace(match[1]), Content: strings.TrimSpace(match[2])}
+			},
+			ParseJSON: func(args map[string]interface{}) Action {
+				return &CreateFileAction{Path: strings.TrimSpace(argString(args, "path")), Content: argString(args, "content")}
+			},
+		},
+		{
+			Name:        "execute_command",
+			Description: "Run a command in the working directory",
+			Required:    []string{"command"},
+			Parameters: map[string]ollama.ToolProperty{
+				"command":     {Type: "string", Description: "Command line to run"},
+				"description": {Type: "string", Description: "Short explanation of what the command does"},
+			},
+			ParseJSON: func(args map[string]interface{}) Action {
+				return &ExecuteCommandAction{Command: strings.TrimSpace(argString(args, "command")), Description: argString(args, "description")}
+			},
+		},
+		{
+			Name:        "create_directory",
+			Description: "Create a directory, including any missing parents",
+			Required:    []string{"path"},
+			Parameters: map[string]ollama.ToolProperty{
+				"path": {Type: "string", Description: "Directory path relative to the working directory"},
+			},
+			ParseJSON: func(args map[string]interface{}) Action {
+				return &CreateDirectoryAction{Path: strings.TrimSpace(argString(args, "path"))}
+			},
+		},
+		{
+			Name:        "modify_file",
+			Description: "Replace a piece of text in an existing file. The text must occur once unless occurrence or replace_all is given",
+			Required:    []string{"path", "search", "replace"},
+			Parameters: map[string]ollama.ToolProperty{
+				"path":        {Type: "string", Description: "File path relative to the working directory"},
+				"search":      {Type: "string", Description: "Text to find"},
+				"replace":     {Type: "string", Description: "Text to replace it with"},
+				"match":       {Type: "string", Description: "How to match: auto (default), exact, line or whitespace", Enum: []string{"auto", "exact", "line", "whitespace"}},
+				"occurrence":  {Type: "integer", Description: "Replace only the nth match (1-based)"},
+				"replace_all": {Type: "boolean", Description: "Replace every match"},
+			},
+			ParseXML:  parseModifyFileXML,
+			ParseJSON: parseModifyFileJSON,
+		},
+		{
+			Name:        "read_file",
+			Description: "Read a file and return its content",
+			Required:    []string{"path"},
+			Parameters: map[string]ollama.ToolProperty{
+				"path": {Type: "string", Description: "File path relative to the working directory"},
+			},
+			ParseJSON: func(args map[string]interface{}) Action {
+				return &ReadFileAction{Path: strings.TrimSpace(argString(args, "path"))}
+			},
+		},
+		{
+			Name:        "apply_patch",
+			Description: "Apply a unified diff to one or more files",
+			Required:    []string{"patch"},
+			Parameters: map[string]ollama.ToolProperty{
+				"patch": {Type: "string", Description: "Unified diff with ---/+++ file headers and @@ hunks"},
+				"path":  {Type: "string", Description: "File to patch when the diff has no file headers"},
+			},
+			// Either the diff itself, or <path> and <patch> child tags
+			ParseXML: func(body string) Action {
+				action := &ApplyPatchAction{Patch: body}
+				if child := patchChildRegex.FindStringSubmatch(body); child != nil {
+					action.Path = strings.TrimSpace(child[1])
+					action.Patch = child[2]
+				}
+				action.Patch = trimPatchBody(action.Patch)
+				return action
+			},
+			ParseJSON: func(args map[string]interface{}) Action {
+				return &ApplyPatchAction{Path: strings.TrimSpace(argString(args, "path")), Patch: argString(args, "patch")}
+			},
+		},
+	}
+}
+
+// parseModifyFileXML parses a modify_file tag. Only blank lines are trimmed
+// from the search and replace text so indentation is kept. Optional <match>
+// and <occurrence> (a number or "all") tags follow <replace>.
+func parseModifyFileXML(body string) Action {
+	match := modifyFileBodyRegex.FindStringSubmatch(body)
+	if match == nil {
+		return nil
+	}
+	action := &ModifyFileAction{
+		Path:    strings.TrimSpace(match[1]),
+		Search:  trimBlankLines(match[2]),
+		Replace: trimBlankLines(match[3]),
+	}
+	for _, opt := range modifyOptionRegex.FindAllStringSubmatch(match[4], -1) {
+		value := strings.TrimSpace(opt[2])
+		switch opt[1] {
+		case "match":
+			action.Match = MatchMode(strings.ToLower(value))
+		case "occurrence":
+			if strings.EqualFold(value, "all") {
+				action.ReplaceAll = true
+			} else if n, err := strconv.Atoi(value); err == nil {
+				action.Occurrence = n
+			}
+		}
+	}
+	return action
+}
+
+// parseModifyFileJSON parses modify_file arguments
+func parseModifyFileJSON(args map[string]interface{}) Action {
+	action := &ModifyFileAction{
+		Path:    strings.TrimSpace(argString(args, "path")),
+		Search:  argString(args, "search"),
+		Replace: argString(args, "replace"),
+		Match:   MatchMode(argString(args, "match")),
+	}
+	// JSON numbers decode as float64; models sometimes send strings
+	switch v := args["occurrence"].(type) {
+	case float64:
+		action.Occurrence = int(v)
+	case string:
+		action.Occurrence, _ = strconv.Atoi(v)
+	}
+	switch v := args["replace_all"].(type) {
+	case bool:
+		action.ReplaceAll = v
+	case string:
+		action.ReplaceAll, _ = strconv.ParseBool(v)
+	}
+	return action
+}
+
+// argString returns a string argument, or "" if it is missing or not a string
+func argString(args map[string]interface{}, key string) string {
+	if v, ok := args[key].(string); ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,249 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultCommandTimeout bounds how long a single command may run
+	defaultCommandTimeout = 5 * time.Minute
+	// defaultMaxOutputBytes bounds how much of each output stream is captured
+	// and returned to the model; the terminal still shows everything
+	defaultMaxOutputBytes = 32 * 1024
+)
+
+// CommandOptions controls how EXECUTE_COMMAND actions are run
+type CommandOptions struct {
+	// Shell runs commands with /bin/sh -c, enabling pipes, redirects and
+	// chaining. Otherwise commands are split into words and run directly.
+	Shell bool
+	// Timeout is the maximum run time of a command (0 means no limit)
+	Timeout time.Duration
+	// MaxOutputBytes caps the captured size of stdout and stderr each
+	// (0 means no limit)
+	MaxOutputBytes int
+}
+
+// DefaultCommandOptions returns the options used when none are set
+func DefaultCommandOptions() CommandOptions {
+	return CommandOptions{
+		Timeout:        defaultCommandTimeout,
+		MaxOutputBytes: defaultMaxOutputBytes,
+	}
+}
+
+type commandOptionsKey struct{}
+
+// withCommandOptions returns a context that carries the options used by
+// command actions
+func withCommandOptions(ctx context.Context, opts CommandOptions) context.Context {
+	return context.WithValue(ctx, commandOptionsKey{}, opts)
+}
+
+// commandOptions returns the options carried by ctx, or the defaults
+func commandOptions(ctx context.Context) CommandOptions {
+	if opts, ok := ctx.Value(commandOptionsKey{}).(CommandOptions); ok {
+		return opts
+	}
+	return DefaultCommandOptions()
+}
+
+// commandLine is a command split into simple commands at shell operators
+type commandLine struct {
+	segments     [][]string // words of each simple command
+	expanded     [][]bool   // whether each word is subject to parameter or glob expansion
+	operators    []string   // operators found outside quotes
+	substitution bool       // contains $(...) or `...`
+}
+
+// parseCommandLine splits a command into words following POSIX shell quoting:
+// single quotes are literal, double quotes allow backslash escapes of $ ` " \,
+// and a backslash outside quotes escapes the next character. Unquoted | & ;
+// newlines, parentheses and command substitutions start a new simple command;
+// redirections stay with the command they belong to.
+func parseCommandLine(command string) (*commandLine, error) {
+	line := &commandLine{}
+	var words []string
+	var expanded []bool
+	var word strings.Builder
+	inWord, wordExpanded := false, false
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			expanded = append(expanded, wordExpanded)
+			word.Reset()
+			inWord, wordExpanded = false, false
+		}
+	}
+	endSegment := func() {
+		endWord()
+		if len(words) > 0 {
+			line.segments = append(line.segments, words)
+			line.expanded = append(line.expanded, expanded)
+			words, expanded = nil, nil
+		}
+	}
+
+	runes := []rune(strings.TrimSpace(command))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			}
+			inWord = true
+
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord = true
+
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\", runes[i+1]) {
+					i++
+				} else if runes[i] == '`' || (runes[i] == '$' && i+1 < len(runes) && runes[i+1] == '(') {
+					line.substitution = true
+				} else if runes[i] == '$' {
+					wordExpanded = true
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+
+		case r == ' ' || r == '\t':
+			endWord()
+
+		case r == '\n':
+			line.operators = append(line.operators, "newline")
+			endSegment()
+
+		case r == '(' || r == ')':
+			// Subshells and groups: the commands inside are checked like any other
+			line.operators = append(line.operators, string(r))
+			endSegment()
+
+		case r == '|' || r == '&' || r == ';':
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == r && r != ';' {
+				op += string(r)
+				i++
+			}
+			// "2>&1" and ">&2" are redirections, not background jobs
+			if r == '&' && op == "&" && i > 0 && runes[i-1] == '>' {
+				word.WriteRune(r)
+				inWord = true
+				continue
+			}
+			line.operators = append(line.operators, op)
+			endSegment()
+
+		case r == '<' || r == '>':
+			endWord()
+			line.operators = append(line.operators, string(r))
+			word.WriteRune(r)
+			inWord = true
+
+		case r == '`' || (r == '$' && i+1 < len(runes) && runes[i+1] == '('):
+			// The substituted commands become segments of their own so they
+			// are checked too
+			line.substitution = true
+			line.operators = append(line.operators, string(r))
+			if r == '$' {
+				line.operators = append(line.operators, "(")
+				i++
+			}
+			endSegment()
+
+		default:
+			if strings.ContainsRune("$*?[", r) {
+				wordExpanded = true
+			}
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endSegment()
+
+	return line, nil
+}
+
+// splitCommand splits a command into program and arguments for direct
+// execution. Shell operators are rejected because without a shell they would
+// be passed to the program as literal arguments.
+func splitCommand(command string) ([]string, error) {
+	line, err := parseCommandLine(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(line.operators) > 0 {
+		return nil, fmt.Errorf("command uses shell syntax (%s); enable shell mode to run it", strings.Join(line.operators, " "))
+	}
+	if len(line.segments) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return line.segments[0], nil
+}
+
+// indexRune returns the index of the first r in runes at or after start, or -1
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// cappedBuffer keeps the first max bytes written to it and counts the rest.
+// Writes never fail, so it can sit behind an io.MultiWriter with the terminal.
+type cappedBuffer struct {
+	buf     strings.Builder
+	max     int
+	dropped int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.max > 0 {
+		room := b.max - b.buf.Len()
+		if room < 0 {
+			room = 0
+		}
+		if len(p) > room {
+			b.dropped += len(p) - room
+			p = p[:room]
+		}
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+func (b *cappedBuffer) Len() int {
+	return b.buf.Len()
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// Reset clears the buffer and sets a new cap
+func (b *cappedBuffer) Reset(max int) {
+	b.buf.Reset()
+	b.max = max
+	b.dropped = 0
+}
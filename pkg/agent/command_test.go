@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{"go build ./...", []string{"go", "build", "./..."}, false},
+		{`git commit -m "initial commit"`, []string{"git", "commit", "-m", "initial commit"}, false},
+		{`echo 'it''s'`, []string{"echo", "its"}, false},
+		{`echo "say \"hi\""`, []string{"echo", `say "hi"`}, false},
+		{`echo a\ b`, []string{"echo", "a b"}, false},
+		{`echo "a | b"`, []string{"echo", "a | b"}, false},
+		{"go test ./... | tee out.txt", nil, true},
+		{"make && make install", nil, true},
+		{"ls > files.txt", nil, true},
+		{"go build\nrm -rf ~", nil, true},
+		{"(cd sub)", nil, true},
+		{"go build ./...\n", []string{"go", "build", "./..."}, false},
+		{`echo "unterminated`, nil, true},
+		{"   ", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := splitCommand(tt.command)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("splitCommand(%q) error = %v, wantErr %v", tt.command, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestParseCommandLine_Segments(t *testing.T) {
+	line, err := parseCommandLine(`go test ./... 2>&1 | grep -v "ok |" && echo done; rm -rf $(pwd)`)
+	if err != nil {
+		t.Fatalf("parseCommandLine failed: %v", err)
+	}
+
+	var programs []string
+	for _, words := range line.segments {
+		programs = append(programs, words[0])
+	}
+	if want := []string{"go", "grep", "echo", "rm", "pwd"}; !reflect.DeepEqual(programs, want) {
+		t.Errorf("Expected programs %q, got %q", want, programs)
+	}
+	if !line.substitution {
+		t.Error("Expected command substitution to be detected")
+	}
+}
+
+func TestExecuteCommandAction_ShellMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	action := &ExecuteCommandAction{Command: "echo hello | tr a-z A-Z && echo oops >&2"}
+
+	// Direct mode refuses shell syntax
+	if err := action.Execute(context.Background(), tmpDir); err == nil {
+		t.Fatal("Expected shell syntax to be rejected without shell mode")
+	}
+
+	ctx := withCommandOptions(context.Background(), CommandOptions{Shell: true})
+	if err := action.Execute(ctx, tmpDir); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	output := action.Output()
+	if !strings.Contains(output, "stdout:\nHELLO") || !strings.Contains(output, "stderr:\noops") {
+		t.Errorf("Unexpected output:\n%s", output)
+	}
+	if action.ExitCode() != 0 {
+		t.Errorf("Expected exit code 0, got %d", action.ExitCode())
+	}
+}
+
+func TestExecuteCommandAction_ExitCode(t *testing.T) {
+	action := &ExecuteCommandAction{Command: `sh -c "exit 3"`}
+	if err := action.Execute(context.Background(), t.TempDir()); err == nil {
+		t.Fatal("Expected a failing command to return an error")
+	}
+	if action.ExitCode() != 3 {
+		t.Errorf("Expected exit code 3, got %d", action.ExitCode())
+	}
+}
+
+func TestExecuteCommandAction_Timeout(t *testing.T) {
+	action := &ExecuteCommandAction{Command: "sleep 5"}
+	ctx := withCommandOptions(context.Background(), CommandOptions{Timeout: 100 * time.Millisecond})
+
+	err := action.Execute(ctx, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	if action.Duration() >= 5*time.Second {
+		t.Errorf("Expected the command to be stopped early, took %s", action.Duration())
+	}
+	if !strings.Contains(action.Output(), "timed out: true") {
+		t.Errorf("Expected the timeout in the output, got:\n%s", action.Output())
+	}
+}
+
+func TestExecuteCommandAction_TimeoutKillsChildren(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("checks processes through /proc")
+	}
+	tmpDir := t.TempDir()
+	action := &ExecuteCommandAction{Command: "sleep 30 & echo $! > child.pid; wait"}
+	ctx := withCommandOptions(context.Background(), CommandOptions{Shell: true, Timeout: 200 * time.Millisecond})
+
+	if err := action.Execute(ctx, tmpDir); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	pid, err := os.ReadFile(filepath.Join(tmpDir, "child.pid"))
+	if err != nil {
+		t.Fatalf("Failed to read the child's pid: %v", err)
+	}
+
+	// The child is gone once it has been reaped, or a zombie until then
+	stat := filepath.Join("/proc", strings.TrimSpace(string(pid)), "stat")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, err := os.ReadFile(stat)
+		if err != nil {
+			break
+		}
+		fields := strings.Fields(string(data))
+		if len(fields) > 2 && (fields[2] == "Z" || fields[2] == "X") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the child process to be killed, it is still running: %s", data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExecuteCommandAction_OutputCap(t *testing.T) {
+	action := &ExecuteCommandAction{Command: "printf %01000d 0"}
+	ctx := withCommandOptions(context.Background(), CommandOptions{MaxOutputBytes: 100})
+
+	if err := action.Execute(ctx, t.TempDir()); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if action.stdout.Len() != 100 {
+		t.Errorf("Expected 100 captured bytes, got %d", action.stdout.Len())
+	}
+	if !strings.Contains(action.Output(), "900 more bytes not shown") {
+		t.Errorf("Expected a truncation note, got:\n%s", action.Output())
+	}
+}
@@ -0,0 +1,219 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// CompactionStrategy selects how the conversation history is shrunk when the
+// prompt approaches the model's context window
+type CompactionStrategy string
+
+const (
+	// CompactionNone never modifies the history
+	CompactionNone CompactionStrategy = "none"
+	// CompactionTruncate drops the oldest exchanges
+	CompactionTruncate CompactionStrategy = "truncate"
+	// CompactionSummarize replaces the oldest exchanges with a summary
+	// produced by a secondary model call
+	CompactionSummarize CompactionStrategy = "summarize"
+)
+
+const (
+	// defaultKeepRecent is the number of most recent exchanges that are
+	// never compacted
+	defaultKeepRecent = 4
+	// compactionThreshold is the fraction of the context window at which
+	// compaction kicks in, leaving room for the response
+	compactionThreshold = 0.8
+	// charsPerToken is a rough, model-agnostic estimate used for budgeting
+	charsPerToken = 4
+	// messageOverheadTokens approximates the chat template tokens per message
+	messageOverheadTokens = 4
+)
+
+const summaryPrompt = `Summarize the conversation below so it can replace the original messages in a coding assistant's context.
+Keep every fact needed to continue the work: the user's goals, decisions made, files created or changed, commands run and their outcomes, and open questions.
+Be concise and write in plain prose.`
+
+// summaryPrefix marks the history message that holds a compaction summary
+const summaryPrefix = "Summary of the earlier conversation:\n"
+
+// estimateTokens roughly estimates the number of tokens in text
+func estimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// estimateMessageTokens roughly estimates the prompt size of messages
+func estimateMessageTokens(messages []ollama.ChatMessage) int {
+	total := 0
+	for _, m := range messages {
+		total += messageOverheadTokens + estimateTokens(m.Content)
+		for _, call := range m.ToolCalls {
+			total += estimateTokens(call.Function.Name) + estimateTokens(fmt.Sprint(call.Function.Arguments))
+		}
+	}
+	return total
+}
+
+// exchangeStarts returns the indexes of the user messages that start each
+// exchange in history
+func exchangeStarts(history []ollama.ChatMessage) []int {
+	var starts []int
+	for i, m := range history {
+		if m.Role == "user" {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// SetCompactionStrategy sets how the history is compacted when the prompt
+// nears the model's context window
+func (a *Agent) SetCompactionStrategy(strategy CompactionStrategy) {
+	a.compaction = strategy
+}
+
+// SetKeepRecentExchanges sets how many of the most recent exchanges are kept
+// intact by compaction. Values below 1 reset it to the default.
+func (a *Agent) SetKeepRecentExchanges(n int) {
+	if n < 1 {
+		n = defaultKeepRecent
+	}
+	a.keepRecent = n
+}
+
+// SetSummaryModel sets the model used to summarize history. An empty name uses
+// the agent's current model.
+func (a *Agent) SetSummaryModel(model string) {
+	a.summaryModel = model
+}
+
+// contextWindow returns the context window of the current model in tokens, or
+// 0 if it is unknown
+func (a *Agent) contextWindow() int {
+	if a.modelParams == nil {
+		return 0
+	}
+	return a.modelParams.ContextLength
+}
+
+// promptTokens estimates the size of the next prompt
+func (a *Agent) promptTokens() int {
+	return estimateMessageTokens(a.buildMessages())
+}
+
+// maybeCompact compacts the history if the estimated prompt exceeds the
+// budget. It is a no-op when the context window is unknown.
+func (a *Agent) maybeCompact(ctx context.Context) {
+	window := a.contextWindow()
+	if a.compaction == CompactionNone || window == 0 {
+		return
+	}
+
+	budget := int(float64(window) * compactionThreshold)
+	if a.promptTokens() <= budget {
+		return
+	}
+
+	if err := a.compactHistory(ctx, budget); err != nil {
+		a.emit(Event{Type: EventError, Err: fmt.Errorf("history compaction failed: %w", err)})
+	}
+}
+
+// compactHistory shrinks the history with the configured strategy, keeping
+// the system prompt and the most recent exchanges. A budget of 0 compacts
+// everything older than the kept exchanges.
+func (a *Agent) compactHistory(ctx context.Context, budget int) error {
+	starts := exchangeStarts(a.conversationHistory)
+	if len(starts) <= a.keepRecent {
+		return fmt.Errorf("nothing to compact: only %d exchange(s) in history", len(starts))
+	}
+	before := a.promptTokens()
+
+	// Everything before this index may be compacted
+	limit := starts[len(starts)-a.keepRecent]
+
+	switch a.compaction {
+	case CompactionSummarize:
+		if err := a.summarizeHistory(ctx, limit); err != nil {
+			a.emit(Event{Type: EventInfo, Text: fmt.Sprintf("⚠️  Summarization failed (%v); truncating instead", err)})
+			a.truncateHistory(limit, budget)
+		}
+	default:
+		a.truncateHistory(limit, budget)
+	}
+
+	a.emit(Event{Type: EventInfo, Text: fmt.Sprintf("🗜️  Compacted history: ~%d → ~%d tokens (%s)", before, a.promptTokens(), a.compaction)})
+	return nil
+}
+
+// truncateHistory drops the oldest exchanges before limit until the prompt
+// fits the budget
+func (a *Agent) truncateHistory(limit, budget int) {
+	history := a.conversationHistory
+	drop := 0
+	for _, start := range exchangeStarts(history[:limit]) {
+		drop = start
+		if budget > 0 && estimateMessageTokens(history[drop:])+a.systemPromptTokens() <= budget {
+			break
+		}
+	}
+	if budget == 0 || estimateMessageTokens(history[drop:])+a.systemPromptTokens() > budget {
+		drop = limit
+	}
+
+	a.conversationHistory = append([]ollama.ChatMessage(nil), history[drop:]...)
+}
+
+// summarizeHistory replaces the history before limit with a single summary
+// message produced by the summary model
+func (a *Agent) summarizeHistory(ctx context.Context, limit int) error {
+	var transcript strings.Builder
+	for _, m := range a.conversationHistory[:limit] {
+		fmt.Fprintf(&transcript, "%s: %s\n\n", m.Role, m.Content)
+	}
+
+	model := a.summaryModel
+	if model == "" {
+		model = a.modelName
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := a.client.ChatEvents(streamCtx, &ollama.ChatRequest{
+		Model: model,
+		Messages: []ollama.ChatMessage{
+			{Role: "system", Content: summaryPrompt},
+			{Role: "user", Content: transcript.String()},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	summary, _, _, err := consumeEvents(streamCtx, events, func(string) error { return nil })
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(summary) == "" {
+		return fmt.Errorf("summary model returned an empty response")
+	}
+
+	history := []ollama.ChatMessage{{Role: "system", Content: summaryPrefix + strings.TrimSpace(summary)}}
+	a.conversationHistory = append(history, a.conversationHistory[limit:]...)
+	return nil
+}
+
+// systemPromptTokens estimates the size of the system message, including the
+// description of registered actions
+func (a *Agent) systemPromptTokens() int {
+	system := a.systemMessage()
+	if system == "" {
+		return 0
+	}
+	return messageOverheadTokens + estimateTokens(system)
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// newHistory builds n user/assistant exchanges with messages of the given size
+func newHistory(n, size int) []ollama.ChatMessage {
+	var history []ollama.ChatMessage
+	for i := 0; i < n; i++ {
+		history = append(history,
+			ollama.ChatMessage{Role: "user", Content: fmt.Sprintf("question %d %s", i, strings.Repeat("q", size))},
+			ollama.ChatMessage{Role: "assistant", Content: fmt.Sprintf("answer %d %s", i, strings.Repeat("a", size))},
+		)
+	}
+	return history
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := estimateTokens(""); got != 0 {
+		t.Errorf("Expected 0 tokens for empty text, got %d", got)
+	}
+	if got := estimateTokens(strings.Repeat("x", 400)); got != 100 {
+		t.Errorf("Expected 100 tokens, got %d", got)
+	}
+}
+
+func TestMaybeCompact_Truncate(t *testing.T) {
+	agent := &Agent{
+		systemPrompt:        "You are helpful",
+		modelParams:         &ModelParameters{ContextLength: 1000},
+		conversationHistory: newHistory(10, 400),
+		compaction:          CompactionTruncate,
+		keepRecent:          2,
+		out:                 io.Discard,
+	}
+
+	agent.maybeCompact(context.Background())
+
+	if tokens := agent.promptTokens(); tokens > 800 {
+		t.Errorf("Expected prompt to fit the budget, got ~%d tokens", tokens)
+	}
+	starts := exchangeStarts(agent.conversationHistory)
+	if len(starts) < 2 {
+		t.Fatalf("Expected the 2 most recent exchanges to be kept, got %d", len(starts))
+	}
+	last := agent.conversationHistory[len(agent.conversationHistory)-1]
+	if !strings.HasPrefix(last.Content, "answer 9") {
+		t.Errorf("Expected the latest exchange to be kept, got %q", last.Content[:10])
+	}
+}
+
+func TestMaybeCompact_CountsActionDescriptions(t *testing.T) {
+	agent := &Agent{
+		modelParams:         &ModelParameters{ContextLength: 1000},
+		conversationHistory: newHistory(10, 100),
+		out:                 io.Discard,
+	}
+	WithCompaction(CompactionTruncate)(agent)
+	WithKeepRecent(1)(agent)
+	err := agent.RegisterAction(ActionDefinition{
+		Name:        "run_tests",
+		Description: strings.Repeat("Run the test suite. ", 60),
+		Execute:     func(context.Context, string, map[string]string) (string, error) { return "", nil },
+	})
+	if err != nil {
+		t.Fatalf("RegisterAction failed: %v", err)
+	}
+
+	agent.maybeCompact(context.Background())
+
+	if tokens := agent.promptTokens(); tokens > 800 {
+		t.Errorf("Expected the prompt, action descriptions included, to fit the budget, got ~%d tokens", tokens)
+	}
+}
+
+func TestMaybeCompact_UnderBudget(t *testing.T) {
+	agent := &Agent{
+		modelParams:         &ModelParameters{ContextLength: 100000},
+		conversationHistory: newHistory(10, 10),
+		compaction:          CompactionTruncate,
+		keepRecent:          2,
+		out:                 io.Discard,
+	}
+
+	agent.maybeCompact(context.Background())
+
+	if len(agent.conversationHistory) != 20 {
+		t.Errorf("Expected history to be untouched, got %d messages", len(agent.conversationHistory))
+	}
+}
+
+func TestCompactHistory_Summarize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"The user asked eight questions."},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer server.Close()
+
+	agent := &Agent{
+		client:              ollama.NewClient(server.URL),
+		modelName:           "test-model",
+		conversationHistory: newHistory(10, 10),
+		compaction:          CompactionSummarize,
+		keepRecent:          2,
+		out:                 io.Discard,
+	}
+
+	if err := agent.compactHistory(context.Background(), 0); err != nil {
+		t.Fatalf("compactHistory failed: %v", err)
+	}
+
+	if len(agent.conversationHistory) != 5 {
+		t.Fatalf("Expected summary plus 2 exchanges (5 messages), got %d", len(agent.conversationHistory))
+	}
+	summary := agent.conversationHistory[0]
+	if summary.Role != "system" || !strings.Contains(summary.Content, "eight questions") {
+		t.Errorf("Unexpected summary message: %+v", summary)
+	}
+}
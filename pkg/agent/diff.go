@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines shown around changes
+	diffContextLines = 3
+	// maxDiffCells bounds the size of the line-matching table; larger
+	// changes are shown as a whole-block replacement
+	maxDiffCells = 4_000_000
+)
+
+// diffOp is one line of an edit script
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+	old  int // line index in the old text (for ' ' and '-')
+	new  int // line index in the new text (for ' ' and '+')
+}
+
+// unifiedDiff returns a unified diff turning oldText into newText, or an
+// empty string when they are equal. An empty oldName means the file is new.
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	oldLines, oldNoEOL := splitDiffLines(oldText)
+	newLines, newNoEOL := splitDiffLines(newText)
+	ops := diffLines(oldLines, newLines)
+
+	var b strings.Builder
+	if oldName == "" {
+		b.WriteString("--- /dev/null\n")
+	} else {
+		fmt.Fprintf(&b, "--- a/%s\n", oldName)
+	}
+	fmt.Fprintf(&b, "+++ b/%s\n", newName)
+
+	for start := 0; start < len(ops); {
+		// Find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+
+		// Extend the hunk until a run of unchanged lines is long enough to
+		// separate it from the next change
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContextLines {
+				break
+			}
+			end = run
+		}
+
+		from := max(start-diffContextLines, 0)
+		to := min(end+diffContextLines, len(ops))
+		writeHunk(&b, ops[from:to], len(oldLines), len(newLines), oldNoEOL, newNoEOL)
+		start = to
+	}
+
+	return b.String()
+}
+
+// writeHunk writes one hunk with its header
+func writeHunk(b *strings.Builder, ops []diffOp, oldLen, newLen int, oldNoEOL, newNoEOL bool) {
+	oldStart, newStart := -1, -1
+	oldCount, newCount := 0, 0
+	for _, op := range ops {
+		if op.kind != '+' {
+			if oldStart < 0 {
+				oldStart = op.old
+			}
+			oldCount++
+		}
+		if op.kind != '-' {
+			if newStart < 0 {
+				newStart = op.new
+			}
+			newCount++
+		}
+	}
+	// Empty ranges are numbered after the line they follow
+	if oldStart < 0 {
+		oldStart = ops[0].old - 1
+	}
+	if newStart < 0 {
+		newStart = ops[0].new - 1
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, op := range ops {
+		fmt.Fprintf(b, "%c%s\n", op.kind, op.text)
+		lastOld := op.kind != '+' && op.old == oldLen-1 && oldNoEOL
+		lastNew := op.kind != '-' && op.new == newLen-1 && newNoEOL
+		if lastOld || lastNew {
+			b.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a 0-based start and a line count as a hunk range
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitDiffLines splits text into lines and reports whether the last line
+// lacks a trailing newline
+func splitDiffLines(text string) ([]string, bool) {
+	if text == "" {
+		return nil, false
+	}
+	noEOL := !strings.HasSuffix(text, "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), noEOL
+}
+
+// diffLines returns an edit script turning a into b, based on the longest
+// common subsequence of lines
+func diffLines(a, b []string) []diffOp {
+	// Common prefix and suffix need no matching
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, diffOp{kind: ' ', text: a[i], old: i, new: i})
+	}
+
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		for i, line := range midA {
+			ops = append(ops, diffOp{kind: '-', text: line, old: prefix + i, new: prefix})
+		}
+		for j, line := range midB {
+			ops = append(ops, diffOp{kind: '+', text: line, old: prefix + len(midA), new: prefix + j})
+		}
+	} else {
+		ops = append(ops, lcsDiff(midA, midB, prefix)...)
+	}
+
+	for k := 0; k < suffix; k++ {
+		i, j := len(a)-suffix+k, len(b)-suffix+k
+		ops = append(ops, diffOp{kind: ' ', text: a[i], old: i, new: j})
+	}
+	return ops
+}
+
+// lcsDiff diffs a and b with a longest-common-subsequence table. offset is
+// added to line indices.
+func lcsDiff(a, b []string, offset int) []diffOp {
+	n, m := len(a), len(b)
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', text: a[i], old: offset + i, new: offset + j})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] > lcs[i+1][j]):
+			ops = append(ops, diffOp{kind: '+', text: b[j], old: offset + i, new: offset + j})
+			j++
+		default:
+			ops = append(ops, diffOp{kind: '-', text: a[i], old: offset + i, new: offset + j})
+			i++
+		}
+	}
+	return ops
+}
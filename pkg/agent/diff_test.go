@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"
+	newText := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello, world\")\n}\n"
+
+	want := "--- a/main.go\n" +
+		"+++ b/main.go\n" +
+		"@@ -3,5 +3,5 @@\n" +
+		" import \"fmt\"\n" +
+		" \n" +
+		" func main() {\n" +
+		"-\tfmt.Println(\"hello\")\n" +
+		"+\tfmt.Println(\"hello, world\")\n" +
+		" }\n"
+	if got := unifiedDiff("main.go", "main.go", oldText, newText); got != want {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiff_NewFile(t *testing.T) {
+	got := unifiedDiff("", "a.txt", "", "one\ntwo")
+
+	want := `--- /dev/null
++++ b/a.txt
+@@ -0,0 +1,2 @@
++one
++two
+\ No newline at end of file
+`
+	if got != want {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnifiedDiff_SeparateHunks(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 0; i < 20; i++ {
+		line := string(rune('a' + i))
+		oldLines = append(oldLines, line)
+		if i == 1 || i == 17 {
+			line = strings.ToUpper(line)
+		}
+		newLines = append(newLines, line)
+	}
+
+	got := unifiedDiff("x", "x", strings.Join(oldLines, "\n")+"\n", strings.Join(newLines, "\n")+"\n")
+	if n := strings.Count(got, "@@ -"); n != 2 {
+		t.Fatalf("Expected 2 hunks, got %d:\n%s", n, got)
+	}
+	if !strings.Contains(got, "@@ -1,5 +1,5 @@") || !strings.Contains(got, "@@ -15,6 +15,6 @@") {
+		t.Errorf("Unexpected hunk headers:\n%s", got)
+	}
+
+	if unifiedDiff("x", "x", "same\n", "same\n") != "" {
+		t.Error("Expected no diff for equal texts")
+	}
+}
@@ -0,0 +1,276 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// EventType identifies what an Event reports
+type EventType string
+
+const (
+	// EventTurnStarted is emitted before each call to the model
+	EventTurnStarted EventType = "turn_started"
+	// EventToken carries a chunk of the streamed response
+	EventToken EventType = "token"
+	// EventStats carries the statistics of a finished model response
+	EventStats EventType = "stats"
+	// EventActionDetected is emitted when an action appears in a response
+	// that is still streaming
+	EventActionDetected EventType = "action_detected"
+	// EventActions lists the actions found in a finished response
+	EventActions EventType = "actions"
+	// EventActionStarted is emitted before an action in a batch runs
+	EventActionStarted EventType = "action_started"
+	// EventActionResult reports the outcome of an action in a batch
+	EventActionResult EventType = "action_result"
+	// EventInfo carries a progress message, such as a tip or a summary
+	EventInfo EventType = "info"
+	// EventError reports a problem that did not stop the agent
+	EventError EventType = "error"
+)
+
+// Event is something the agent reports while it works. Only the fields that
+// belong to the event's type are set.
+type Event struct {
+	Type      EventType
+	Time      time.Time
+	Iteration int    // EventTurnStarted: 1 for the first call of a turn
+	Model     string // EventTurnStarted, EventStats
+	Text      string // EventToken, EventInfo
+
+	Action  Action        // EventActionDetected, EventActionStarted
+	Actions []Action      // EventActions
+	Index   int           // EventActionStarted, EventActionResult: 1-based
+	Total   int           // EventActionStarted, EventActionResult
+	Result  *ActionResult // EventActionResult
+
+	Stats          *ollama.StreamStats // EventStats
+	Messages       int                 // EventStats: messages sent to the model
+	ResponseLength int                 // EventStats: characters in the response
+	ContextLength  int                 // EventStats: the model's context window, if known
+
+	Err error // EventError
+}
+
+// MarshalJSON renders the event as a flat object with the type, the time and
+// the fields that are set; actions are described by their String and tool name
+func (ev Event) MarshalJSON() ([]byte, error) {
+	type actionJSON struct {
+		Action string `json:"action"`
+		Type   string `json:"type,omitempty"`
+	}
+	type statsJSON struct {
+		PromptTokens    int     `json:"prompt_tokens"`
+		ResponseTokens  int     `json:"response_tokens"`
+		TotalDurationMS int64   `json:"total_duration_ms"`
+		LoadDurationMS  int64   `json:"load_duration_ms"`
+		TokensPerSecond float64 `json:"tokens_per_second,omitempty"`
+		DoneReason      string  `json:"done_reason,omitempty"`
+	}
+	describe := func(action Action) *actionJSON {
+		return &actionJSON{Action: action.String(), Type: toolName(action)}
+	}
+
+	out := struct {
+		Type           EventType     `json:"type"`
+		Time           time.Time     `json:"time"`
+		Iteration      int           `json:"iteration,omitempty"`
+		Model          string        `json:"model,omitempty"`
+		Text           string        `json:"text,omitempty"`
+		Action         *actionJSON   `json:"action,omitempty"`
+		Actions        []*actionJSON `json:"actions,omitempty"`
+		Index          int           `json:"index,omitempty"`
+		Total          int           `json:"total,omitempty"`
+		Result         *ActionResult `json:"result,omitempty"`
+		Stats          *statsJSON    `json:"stats,omitempty"`
+		Messages       int           `json:"messages,omitempty"`
+		ResponseLength int           `json:"response_length,omitempty"`
+		ContextLength  int           `json:"context_length,omitempty"`
+		Error          string        `json:"error,omitempty"`
+	}{
+		Type:           ev.Type,
+		Time:           ev.Time,
+		Iteration:      ev.Iteration,
+		Model:          ev.Model,
+		Text:           ev.Text,
+		Index:          ev.Index,
+		Total:          ev.Total,
+		Result:         ev.Result,
+		Messages:       ev.Messages,
+		ResponseLength: ev.ResponseLength,
+		ContextLength:  ev.ContextLength,
+	}
+	if ev.Action != nil {
+		out.Action = describe(ev.Action)
+	}
+	for _, action := range ev.Actions {
+		out.Actions = append(out.Actions, describe(action))
+	}
+	if s := ev.Stats; s != nil {
+		out.Stats = &statsJSON{
+			PromptTokens:    s.PromptEvalCount,
+			ResponseTokens:  s.EvalCount,
+			TotalDurationMS: s.TotalDuration.Milliseconds(),
+			LoadDurationMS:  s.LoadDuration.Milliseconds(),
+			TokensPerSecond: s.TokensPerSecond(),
+			DoneReason:      s.DoneReason,
+		}
+	}
+	if ev.Err != nil {
+		out.Error = ev.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// EventSink receives the events of an agent or executor. Events are delivered
+// in order from the goroutine doing the work, so a sink should not block.
+type EventSink interface {
+	HandleEvent(ev Event)
+}
+
+// EventSinkFunc adapts a function to an EventSink
+type EventSinkFunc func(ev Event)
+
+// HandleEvent calls f(ev)
+func (f EventSinkFunc) HandleEvent(ev Event) {
+	f(ev)
+}
+
+// multiSink hands every event to several sinks in turn
+type multiSink []EventSink
+
+// MultiSink returns a sink that passes every event to each of sinks
+func MultiSink(sinks ...EventSink) EventSink {
+	return multiSink(sinks)
+}
+
+// HandleEvent passes ev to each sink
+func (m multiSink) HandleEvent(ev Event) {
+	for _, sink := range m {
+		sink.HandleEvent(ev)
+	}
+}
+
+// emit stamps ev with the current time and hands it to sink
+func emit(sink EventSink, ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	sink.HandleEvent(ev)
+}
+
+// TerminalRenderer writes events as the human-readable progress shown in the
+// REPL. Tokens are not written; the caller of SendMessage prints those as
+// they stream.
+type TerminalRenderer struct {
+	w io.Writer
+}
+
+// NewTerminalRenderer creates a renderer writing to w
+func NewTerminalRenderer(w io.Writer) *TerminalRenderer {
+	return &TerminalRenderer{w: w}
+}
+
+// HandleEvent writes ev to the terminal
+func (r *TerminalRenderer) HandleEvent(ev Event) {
+	switch ev.Type {
+	case EventStats:
+		r.printStats(ev)
+	case EventActionDetected:
+		fmt.Fprintf(r.w, "\n📋 Action detected: %s\n", ev.Action.String())
+	case EventActions:
+		fmt.Fprintf(r.w, "\n\n📋 Detected %d action(s):\n", len(ev.Actions))
+		for i, action := range ev.Actions {
+			fmt.Fprintf(r.w, "  %d. %s\n", i+1, action.String())
+		}
+	case EventActionStarted:
+		fmt.Fprintf(r.w, "\n[%d/%d] %s\n", ev.Index, ev.Total, ev.Action.String())
+	case EventActionResult:
+		switch ev.Result.Status {
+		case StatusSucceeded:
+			fmt.Fprintln(r.w, "✓ Completed")
+		case StatusSkipped:
+			fmt.Fprintln(r.w, "⏭️  Skipped")
+		case StatusFailed:
+			fmt.Fprintf(r.w, "✖ %s\n", capitalize(ev.Result.Err.Error()))
+		}
+	case EventInfo:
+		fmt.Fprintf(r.w, "\n%s\n", ev.Text)
+	case EventError:
+		fmt.Fprintf(r.w, "✖ %s\n", capitalize(ev.Err.Error()))
+	}
+}
+
+// printStats prints statistics about a model response
+func (r *TerminalRenderer) printStats(ev Event) {
+	stats := ev.Stats
+	fmt.Fprintf(r.w, "\n📊 Model Stats:\n")
+
+	// Model context capacity
+	if ev.ContextLength > 0 {
+		fmt.Fprintf(r.w, "  • Model Context: %d tokens\n", ev.ContextLength)
+	}
+
+	// Usage statistics
+	fmt.Fprintf(r.w, "  • Context Messages: %d\n", ev.Messages)
+	fmt.Fprintf(r.w, "  • Response Length: %d chars\n", ev.ResponseLength)
+	fmt.Fprintf(r.w, "  • Total Duration: %dms\n", stats.TotalDuration.Milliseconds())
+	fmt.Fprintf(r.w, "  • Load Duration: %dms\n", stats.LoadDuration.Milliseconds())
+	if tps := stats.TokensPerSecond(); tps > 0 {
+		fmt.Fprintf(r.w, "  • Tokens: %d prompt, %d generated (%.1f tokens/s)\n",
+			stats.PromptEvalCount, stats.EvalCount, tps)
+	}
+
+	// Context window usage
+	usedTokens := stats.PromptEvalCount + stats.EvalCount
+	if len(stats.Context) > 0 {
+		usedTokens = len(stats.Context)
+	}
+	if usedTokens > 0 {
+		if ev.ContextLength > 0 {
+			usagePercent := float64(usedTokens) / float64(ev.ContextLength) * 100
+			fmt.Fprintf(r.w, "  • Context Usage: %d/%d tokens (%.1f%%)\n",
+				usedTokens, ev.ContextLength, usagePercent)
+		} else {
+			fmt.Fprintf(r.w, "  • Context Tokens Used: %d\n", usedTokens)
+		}
+	} else {
+		fmt.Fprintf(r.w, "  • Context Usage: No context used yet\n")
+	}
+}
+
+// capitalize upper-cases the first letter of an error message for display
+func capitalize(s string) string {
+	first, size := utf8.DecodeRuneInString(s)
+	if size == 0 {
+		return s
+	}
+	return string(unicode.ToUpper(first)) + s[size:]
+}
+
+// JSONRenderer writes every event, tokens included, as one JSON object per
+// line for machine consumption. It is safe for concurrent use.
+type JSONRenderer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONRenderer creates a renderer writing JSON lines to w
+func NewJSONRenderer(w io.Writer) *JSONRenderer {
+	return &JSONRenderer{enc: json.NewEncoder(w)}
+}
+
+// HandleEvent writes ev as a line of JSON
+func (r *JSONRenderer) HandleEvent(ev Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(ev)
+}
@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// eventTypes returns the type of each event
+func eventTypes(events []Event) []EventType {
+	types := make([]EventType, len(events))
+	for i, ev := range events {
+		types[i] = ev.Type
+	}
+	return types
+}
+
+func TestExecutor_Events(t *testing.T) {
+	var events []Event
+	executor := NewExecutor(t.TempDir())
+	executor.SetEventSink(EventSinkFunc(func(ev Event) { events = append(events, ev) }))
+
+	executor.Execute(context.Background(), []Action{
+		&CreateFileAction{Path: "notes.txt", Content: "hello"},
+		&ReadFileAction{Path: "missing.txt"},
+	})
+
+	want := []EventType{EventActionStarted, EventActionResult, EventActionStarted, EventActionResult}
+	if got := eventTypes(events); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	if events[1].Index != 1 || events[1].Total != 2 || events[1].Result.Status != StatusSucceeded {
+		t.Errorf("Unexpected first result event: %+v", events[1])
+	}
+	if events[3].Index != 2 || events[3].Result.Status != StatusFailed {
+		t.Errorf("Unexpected second result event: %+v", events[3])
+	}
+	if events[0].Time.IsZero() {
+		t.Error("Expected events to be timestamped")
+	}
+}
+
+func TestTerminalRenderer_Executor(t *testing.T) {
+	var out bytes.Buffer
+	executor := NewExecutor(t.TempDir())
+	executor.SetOutput(&out)
+
+	executor.Execute(context.Background(), []Action{
+		&CreateFileAction{Path: "notes.txt", Content: "hello"},
+		&ReadFileAction{Path: "missing.txt"},
+	})
+
+	for _, want := range []string{
+		"[1/2] CREATE_FILE: notes.txt",
+		"✓ Completed",
+		"[2/2] READ_FILE: missing.txt",
+		"✖ Execution failed for action 2:",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestJSONRenderer(t *testing.T) {
+	var out bytes.Buffer
+	renderer := NewJSONRenderer(&out)
+
+	result := ActionResult{Action: &ReadFileAction{Path: "a.txt"}, Status: StatusFailed, Err: fmt.Errorf("boom")}
+	emit(renderer, Event{Type: EventToken, Text: "Hi"})
+	emit(renderer, Event{Type: EventActionResult, Index: 1, Total: 1, Result: &result})
+	emit(renderer, Event{Type: EventStats, Stats: &ollama.StreamStats{PromptEvalCount: 7, EvalCount: 3}, Messages: 2})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d:\n%s", len(lines), out.String())
+	}
+
+	var token struct {
+		Type string `json:"type"`
+		Time string `json:"time"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &token); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", lines[0], err)
+	}
+	if token.Type != "token" || token.Text != "Hi" || token.Time == "" {
+		t.Errorf("Unexpected token event: %s", lines[0])
+	}
+
+	var res struct {
+		Type   string `json:"type"`
+		Index  int    `json:"index"`
+		Result struct {
+			Action string `json:"action"`
+			Type   string `json:"type"`
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &res); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", lines[1], err)
+	}
+	if res.Type != "action_result" || res.Index != 1 || res.Result.Type != "read_file" ||
+		res.Result.Status != "failed" || res.Result.Error != "boom" {
+		t.Errorf("Unexpected result event: %s", lines[1])
+	}
+
+	var stats struct {
+		Messages int `json:"messages"`
+		Stats    struct {
+			PromptTokens   int `json:"prompt_tokens"`
+			ResponseTokens int `json:"response_tokens"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &stats); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", lines[2], err)
+	}
+	if stats.Messages != 2 || stats.Stats.PromptTokens != 7 || stats.Stats.ResponseTokens != 3 {
+		t.Errorf("Unexpected stats event: %s", lines[2])
+	}
+}
+
+func TestAgent_SendMessageEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Let me look. <read_file><path>a.txt</path>"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"</read_file>"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":12,"eval_count":8}`)
+	}))
+	defer server.Close()
+
+	var events []Event
+	agent := &Agent{
+		client:        ollama.NewClient(server.URL),
+		modelName:     "test-model",
+		actionParser:  NewActionParser(),
+		maxIterations: defaultMaxIterations,
+		out:           io.Discard,
+	}
+	agent.SetEventSink(EventSinkFunc(func(ev Event) { events = append(events, ev) }))
+
+	// Without a chunk callback the tokens are only reported as events
+	if err := agent.SendMessage(context.Background(), "What is in a.txt?", nil); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+
+	want := []EventType{EventTurnStarted, EventToken, EventToken, EventActionDetected, EventStats, EventActions, EventInfo}
+	if got := eventTypes(events); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	if events[0].Model != "test-model" || events[0].Iteration != 1 {
+		t.Errorf("Unexpected turn event: %+v", events[0])
+	}
+	if stats := events[4]; stats.Stats.PromptEvalCount != 12 || stats.Messages != 1 {
+		t.Errorf("Unexpected stats event: %+v", stats)
+	}
+	if len(events[5].Actions) != 1 || len(agent.pendingActions) != 1 {
+		t.Errorf("Expected one pending action, got %v", events[5].Actions)
+	}
+}
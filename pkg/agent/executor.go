@@ -0,0 +1,283 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Executor runs batches of actions in a working directory. With a journal
+// attached, the prior state of every file an action touches is recorded so
+// the batch can be undone, or rolled back automatically when an action fails.
+type Executor struct {
+	workDir           string
+	readRoots         []string
+	journal           *Journal
+	rollbackOnFailure bool
+	policy            *CommandPolicy
+	askCommand        AskFunc
+	commandOptions    CommandOptions
+	approve           ApproveFunc
+	prefetched        map[Action]error
+	out               io.Writer
+	events            EventSink
+}
+
+// NewExecutor creates an executor for the given working directory. Progress
+// is discarded unless an output writer is set.
+func NewExecutor(workDir string) *Executor {
+	return &Executor{workDir: workDir, commandOptions: DefaultCommandOptions(), out: io.Discard}
+}
+
+// SetOutput sets where progress and the live output of commands are written
+func (e *Executor) SetOutput(w io.Writer) {
+	e.out = w
+}
+
+// SetEventSink sends progress to sink as events instead of rendering it on
+// the output writer. The live output of commands still goes to the writer.
+func (e *Executor) SetEventSink(sink EventSink) {
+	e.events = sink
+}
+
+// emit reports an event to the executor's sink
+func (e *Executor) emit(ev Event) {
+	if e.events == nil {
+		e.events = NewTerminalRenderer(e.out)
+	}
+	emit(e.events, ev)
+}
+
+type outputKey struct{}
+
+// withOutput returns a context that carries the writer actions report
+// progress to
+func withOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+// outputWriter returns the writer carried by ctx, or io.Discard
+func outputWriter(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(outputKey{}).(io.Writer); ok {
+		return w
+	}
+	return io.Discard
+}
+
+// SetReadRoots allows actions to read (but not write) files under the given
+// directories in addition to the working directory
+func (e *Executor) SetReadRoots(roots ...string) {
+	e.readRoots = roots
+}
+
+// SetCommandPolicy makes the executor check every command against policy
+// before running it. ask is called for commands the policy asks about; when it
+// is nil those commands are refused.
+func (e *Executor) SetCommandPolicy(policy *CommandPolicy, ask AskFunc) {
+	e.policy = policy
+	e.askCommand = ask
+}
+
+// SetCommandOptions sets how commands are run: shell mode, timeout and the
+// output capture limit
+func (e *Executor) SetCommandOptions(opts CommandOptions) {
+	e.commandOptions = opts
+}
+
+// SetApprover makes the executor show each action to approve before running
+// it. Edited actions replace the originals in the slice passed to Execute.
+func (e *Executor) SetApprover(approve ApproveFunc) {
+	e.approve = approve
+}
+
+// SetPrefetched supplies the results of actions that already ran while the
+// response was streaming. Those actions are still reviewed and reported, but
+// not run again.
+func (e *Executor) SetPrefetched(results map[Action]error) {
+	e.prefetched = results
+}
+
+// SetJournal attaches a journal that records file changes for undo
+func (e *Executor) SetJournal(journal *Journal) {
+	e.journal = journal
+}
+
+// SetRollbackOnFailure makes the executor undo a whole batch when any action
+// in it fails
+func (e *Executor) SetRollbackOnFailure(enabled bool) {
+	e.rollbackOnFailure = enabled
+}
+
+// ExecuteActions executes a list of actions in order. See Executor.Execute.
+func ExecuteActions(ctx context.Context, actions []Action, workDir string) ([]ActionResult, error) {
+	return NewExecutor(workDir).Execute(ctx, actions)
+}
+
+// Execute runs the actions in order and returns one result per action. If any
+// action did not succeed the error is a *BatchError.
+func (e *Executor) Execute(ctx context.Context, actions []Action) ([]ActionResult, error) {
+	results := e.run(ctx, actions)
+	return results, newBatchError(results)
+}
+
+// run executes the batch and returns one result per action
+func (e *Executor) run(ctx context.Context, actions []Action) []ActionResult {
+	results := make([]ActionResult, len(actions))
+	for i, action := range actions {
+		results[i] = ActionResult{Action: action, Status: StatusNotRun}
+	}
+	fail := func(i int, status ActionStatus, err error) {
+		results[i].Status = status
+		results[i].Err = err
+	}
+	report := func(i int) {
+		result := results[i]
+		e.emit(Event{Type: EventActionResult, Index: i + 1, Total: len(actions), Result: &result})
+	}
+
+	// Every action resolves its paths through the same sandbox
+	sb, err := NewSandbox(e.workDir, e.readRoots...)
+	if err != nil {
+		e.emit(Event{Type: EventError, Err: err})
+		for i := range actions {
+			fail(i, StatusNotRun, fmt.Errorf("action %d not run: %w", i+1, err))
+			report(i)
+		}
+		return results
+	}
+	ctx = withSandbox(ctx, sb)
+	ctx = withCommandOptions(ctx, e.commandOptions)
+	ctx = withOutput(ctx, e.out)
+
+	// Rolling back needs a journal even if none is kept for undo
+	journal := e.journal
+	if journal == nil && e.rollbackOnFailure {
+		journal = NewJournal()
+	}
+	if journal != nil {
+		journal.begin()
+		defer journal.commit()
+	}
+
+	failed := false
+	aborted := false
+	for i := 0; i < len(actions) && !aborted; i++ {
+		action := actions[i]
+		e.emit(Event{Type: EventActionStarted, Index: i + 1, Total: len(actions), Action: action})
+
+		// Validate
+		if err := action.Validate(); err != nil {
+			// Report and continue with next action
+			fail(i, StatusFailed, fmt.Errorf("validation failed for action %d: %w", i+1, err))
+			report(i)
+			failed = true
+			continue
+		}
+
+		// Let the user review the action, editing it as often as they like
+		decision := ReviewApprove
+		for e.approve != nil {
+			var edited Action
+			decision, edited = e.approve(action, previewAction(ctx, e.workDir, action))
+			if decision != ReviewEdit {
+				break
+			}
+			if err := edited.Validate(); err != nil {
+				e.emit(Event{Type: EventError, Err: fmt.Errorf("edited action is invalid: %w", err)})
+				continue
+			}
+			action = edited
+			actions[i] = edited
+			results[i].Action = edited
+			e.emit(Event{Type: EventActionStarted, Index: i + 1, Total: len(actions), Action: action})
+		}
+		if decision == ReviewSkip {
+			fail(i, StatusSkipped, fmt.Errorf("action %d skipped by user", i+1))
+			report(i)
+			continue
+		}
+		if decision == ReviewAbort {
+			e.emit(Event{Type: EventInfo, Text: fmt.Sprintf("🛑 Aborted; %d action(s) not run", len(actions)-i)})
+			for j := i; j < len(actions); j++ {
+				fail(j, StatusNotRun, fmt.Errorf("action %d not run: batch aborted by user", j+1))
+				report(j)
+			}
+			aborted = true
+			continue
+		}
+
+		// Consult the command policy
+		if cmd, ok := action.(*ExecuteCommandAction); ok && e.policy != nil {
+			if err := e.policy.Check(cmd.Command, e.askCommand); err != nil {
+				fail(i, StatusFailed, fmt.Errorf("command not run for action %d: %w", i+1, err))
+				report(i)
+				failed = true
+				continue
+			}
+		}
+
+		// Snapshot the files the action is about to change. Paths outside the
+		// sandbox are skipped here; the action itself rejects them.
+		if ja, ok := action.(journaledAction); ok && journal != nil {
+			var err error
+			for _, path := range ja.targetPaths() {
+				target, resolveErr := sb.Resolve(path, true)
+				if resolveErr != nil {
+					continue
+				}
+				if err = journal.record(pathWithParents(sb.Root(), target)); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				fail(i, StatusFailed, fmt.Errorf("journaling failed for action %d: %w", i+1, err))
+				report(i)
+				failed = true
+				continue
+			}
+		}
+
+		// Execute, unless the action already ran while the response streamed
+		execute := action.Execute
+		if err, ok := e.prefetched[action]; ok {
+			execute = func(context.Context, string) error { return err }
+		}
+		start := time.Now()
+		err := execute(ctx, e.workDir)
+		results[i].Duration = time.Since(start)
+		if out, ok := action.(ActionOutput); ok {
+			results[i].Output = out.Output()
+		}
+		if err != nil {
+			// Report and continue with next action
+			fail(i, StatusFailed, fmt.Errorf("execution failed for action %d: %w", i+1, err))
+			report(i)
+			failed = true
+			continue
+		}
+
+		results[i].Status = StatusSucceeded
+		if wa, ok := action.(writingAction); ok {
+			results[i].BytesWritten = wa.bytesWritten()
+		}
+		report(i)
+	}
+
+	if failed && e.rollbackOnFailure {
+		restored, err := journal.rollbackCurrent()
+		if err != nil {
+			e.emit(Event{Type: EventError, Err: fmt.Errorf("rollback incomplete: %w", err)})
+		}
+		e.emit(Event{Type: EventInfo, Text: fmt.Sprintf("↩️  Rolled back %d change(s) because an action failed", restored)})
+
+		for i, r := range results {
+			if _, ok := r.Action.(journaledAction); ok && r.Status == StatusSucceeded {
+				fail(i, StatusRolledBack, fmt.Errorf("action %d was rolled back because another action in the batch failed", i+1))
+				report(i)
+			}
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// journalEntry records the state of a path before an action changed it
+type journalEntry struct {
+	path    string // absolute path
+	existed bool
+	isDir   bool
+	content []byte
+	mode    os.FileMode
+}
+
+// journalBatch groups the entries recorded while one batch of actions ran
+type journalBatch struct {
+	time    time.Time
+	entries []journalEntry
+	seen    map[string]bool
+}
+
+// Journal records the prior state of every path touched by file actions so
+// that whole batches can be undone. Commands are not journaled.
+type Journal struct {
+	batches []*journalBatch
+	current *journalBatch
+}
+
+// NewJournal creates an empty journal
+func NewJournal() *Journal {
+	return &Journal{}
+}
+
+// journaledAction is implemented by actions that create, modify or delete the
+// files or directories at targetPaths
+type journaledAction interface {
+	targetPaths() []string
+}
+
+// Len returns the number of batches that can be undone
+func (j *Journal) Len() int {
+	return len(j.batches)
+}
+
+// begin starts recording a new batch
+func (j *Journal) begin() {
+	j.current = &journalBatch{time: time.Now(), seen: make(map[string]bool)}
+}
+
+// commit finishes the current batch; empty batches are discarded
+func (j *Journal) commit() {
+	if j.current != nil && len(j.current.entries) > 0 {
+		j.batches = append(j.batches, j.current)
+	}
+	j.current = nil
+}
+
+// record snapshots each path the first time it is seen in the current batch
+func (j *Journal) record(paths []string) error {
+	if j.current == nil {
+		return fmt.Errorf("no batch in progress")
+	}
+
+	for _, path := range paths {
+		if j.current.seen[path] {
+			continue
+		}
+
+		entry := journalEntry{path: path}
+		info, err := os.Lstat(path)
+		switch {
+		case os.IsNotExist(err):
+			// Restoring means removing whatever the action creates
+		case err != nil:
+			return fmt.Errorf("failed to snapshot %s: %w", path, err)
+		case info.IsDir():
+			entry.existed = true
+			entry.isDir = true
+		default:
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to snapshot %s: %w", path, err)
+			}
+			entry.existed = true
+			entry.content = content
+			entry.mode = info.Mode().Perm()
+		}
+
+		j.current.seen[path] = true
+		j.current.entries = append(j.current.entries, entry)
+	}
+
+	return nil
+}
+
+// rollbackCurrent restores the batch in progress and discards it
+func (j *Journal) rollbackCurrent() (int, error) {
+	if j.current == nil {
+		return 0, nil
+	}
+	batch := j.current
+	j.current = nil
+	return batch.restore()
+}
+
+// Undo restores the state from before the most recent batch and returns the
+// number of paths restored
+func (j *Journal) Undo() (int, error) {
+	if len(j.batches) == 0 {
+		return 0, fmt.Errorf("nothing to undo")
+	}
+
+	batch := j.batches[len(j.batches)-1]
+	j.batches = j.batches[:len(j.batches)-1]
+	return batch.restore()
+}
+
+// UndoAll restores every recorded batch, newest first, and returns the total
+// number of paths restored
+func (j *Journal) UndoAll() (int, error) {
+	if len(j.batches) == 0 {
+		return 0, fmt.Errorf("nothing to undo")
+	}
+
+	total := 0
+	var errs []error
+	for len(j.batches) > 0 {
+		n, err := j.Undo()
+		total += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+// restore puts every entry back in reverse order, so files are restored before
+// the directories that were created to hold them are removed
+func (b *journalBatch) restore() (int, error) {
+	restored := 0
+	var errs []error
+
+	for i := len(b.entries) - 1; i >= 0; i-- {
+		entry := b.entries[i]
+		var err error
+		switch {
+		case !entry.existed:
+			err = os.Remove(entry.path)
+			if os.IsNotExist(err) {
+				err = nil
+			}
+		case entry.isDir:
+			// Existing directories are left untouched
+			continue
+		default:
+			err = os.WriteFile(entry.path, entry.content, entry.mode)
+			if err == nil {
+				err = os.Chmod(entry.path, entry.mode)
+			}
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore %s: %w", entry.path, err))
+			continue
+		}
+		restored++
+	}
+
+	return restored, errors.Join(errs...)
+}
+
+// pathWithParents returns the directories between root and the absolute
+// target path followed by the target itself
+func pathWithParents(root, target string) []string {
+	rel, err := filepath.Rel(root, target)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return []string{target}
+	}
+
+	var paths []string
+	current := root
+	parts := strings.Split(rel, string(filepath.Separator))
+	for _, part := range parts[:len(parts)-1] {
+		current = filepath.Join(current, part)
+		paths = append(paths, current)
+	}
+	return append(paths, target)
+}
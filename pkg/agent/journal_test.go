@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJournal_Undo(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	existing := filepath.Join(tmpDir, "main.go")
+	if err := os.WriteFile(existing, []byte("package main"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	journal := NewJournal()
+	executor := NewExecutor(tmpDir)
+	executor.SetJournal(journal)
+
+	actions := []Action{
+		&ModifyFileAction{Path: "main.go", Search: "main", Replace: "app"},
+		&CreateFileAction{Path: "pkg/models/user.go", Content: "package models"},
+		&CreateDirectoryAction{Path: "docs"},
+	}
+	if _, err := executor.Execute(context.Background(), actions); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if journal.Len() != 1 {
+		t.Fatalf("Expected 1 batch in the journal, got %d", journal.Len())
+	}
+
+	if _, err := journal.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+
+	content, err := os.ReadFile(existing)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "package main" {
+		t.Errorf("Expected original content to be restored, got '%s'", string(content))
+	}
+	for _, path := range []string{"pkg", "docs"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, path)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got err=%v", path, err)
+		}
+	}
+
+	if _, err := journal.Undo(); err == nil {
+		t.Error("Expected an error when there is nothing to undo")
+	}
+}
+
+func TestJournal_UndoAll(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	journal := NewJournal()
+	executor := NewExecutor(tmpDir)
+	executor.SetJournal(journal)
+
+	ctx := context.Background()
+	if _, err := executor.Execute(ctx, []Action{&CreateFileAction{Path: "a.txt", Content: "one"}}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if _, err := executor.Execute(ctx, []Action{&ModifyFileAction{Path: "a.txt", Search: "one", Replace: "two"}}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	if _, err := journal.UndoAll(); err != nil {
+		t.Fatalf("UndoAll failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected a.txt to be removed, got err=%v", err)
+	}
+	if journal.Len() != 0 {
+		t.Errorf("Expected an empty journal, got %d batch(es)", journal.Len())
+	}
+}
+
+func TestExecutor_RollbackOnFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	executor := NewExecutor(tmpDir)
+	executor.SetRollbackOnFailure(true)
+
+	actions := []Action{
+		&CreateFileAction{Path: "created.txt", Content: "hello"},
+		&ModifyFileAction{Path: "missing.txt", Search: "a", Replace: "b"},
+	}
+	results := executor.run(context.Background(), actions)
+
+	if results[0].Status != StatusRolledBack || results[1].Status != StatusFailed {
+		t.Fatalf("Expected rolled_back and failed, got %s and %s", results[0].Status, results[1].Status)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "created.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected created.txt to be rolled back, got err=%v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MatchMode selects how MODIFY_FILE finds its search text
+type MatchMode string
+
+const (
+	// MatchAuto tries exact, then line, then whitespace matching and uses
+	// the first mode that finds the text (the default)
+	MatchAuto MatchMode = "auto"
+	// MatchExact finds the search text byte for byte
+	MatchExact MatchMode = "exact"
+	// MatchLine matches whole lines, ignoring trailing whitespace
+	MatchLine MatchMode = "line"
+	// MatchWhitespace matches whole lines, ignoring all differences in
+	// indentation and spacing; the replacement is re-indented to fit
+	MatchWhitespace MatchMode = "whitespace"
+)
+
+// textSpan is a byte range of a file matched by a search
+type textSpan struct {
+	start, end int
+	indents    map[string]string // search indentation → file indentation
+}
+
+// validateMatchMode checks that a match mode is one of the known values
+func validateMatchMode(mode MatchMode) error {
+	switch mode {
+	case "", MatchAuto, MatchExact, MatchLine, MatchWhitespace:
+		return nil
+	}
+	return fmt.Errorf("unknown match mode %q (use auto, exact, line or whitespace)", mode)
+}
+
+// findMatches returns the non-overlapping matches of search in content for a
+// mode, and the mode that produced them (relevant for auto)
+func findMatches(content, search string, mode MatchMode) ([]textSpan, MatchMode) {
+	switch mode {
+	case MatchExact:
+		return exactMatches(content, search), MatchExact
+	case MatchLine:
+		return lineMatches(content, search, trimTrailing), MatchLine
+	case MatchWhitespace:
+		return lineMatches(content, search, normalizeSpace), MatchWhitespace
+	}
+
+	for _, m := range []MatchMode{MatchExact, MatchLine, MatchWhitespace} {
+		if spans, used := findMatches(content, search, m); len(spans) > 0 {
+			return spans, used
+		}
+	}
+	return nil, MatchAuto
+}
+
+// exactMatches returns every non-overlapping occurrence of search
+func exactMatches(content, search string) []textSpan {
+	var spans []textSpan
+	for offset := 0; ; {
+		i := strings.Index(content[offset:], search)
+		if i < 0 {
+			return spans
+		}
+		start := offset + i
+		spans = append(spans, textSpan{start: start, end: start + len(search)})
+		offset = start + len(search)
+	}
+}
+
+// lineMatches returns the runs of whole lines in content that equal the lines
+// of search after normalizing both with norm. Blank lines around the search
+// text are ignored. A span covers its lines without the final newline.
+func lineMatches(content, search string, norm func(string) string) []textSpan {
+	searchLines := strings.Split(strings.ReplaceAll(search, "\r\n", "\n"), "\n")
+	for len(searchLines) > 0 && strings.TrimSpace(searchLines[0]) == "" {
+		searchLines = searchLines[1:]
+	}
+	for len(searchLines) > 0 && strings.TrimSpace(searchLines[len(searchLines)-1]) == "" {
+		searchLines = searchLines[:len(searchLines)-1]
+	}
+	if len(searchLines) == 0 {
+		return nil
+	}
+	want := make([]string, len(searchLines))
+	for i, line := range searchLines {
+		want[i] = norm(line)
+	}
+
+	// Byte offset of the start of each line
+	lines := strings.Split(content, "\n")
+	starts := make([]int, len(lines)+1)
+	for i, line := range lines {
+		starts[i+1] = starts[i] + len(line) + 1
+	}
+
+	var spans []textSpan
+	for i := 0; i+len(want) <= len(lines); {
+		matched := true
+		for j := range want {
+			if norm(lines[i+j]) != want[j] {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			i++
+			continue
+		}
+
+		indents := make(map[string]string)
+		for j, line := range searchLines {
+			if strings.TrimSpace(line) != "" {
+				indents[leadingSpace(line)] = leadingSpace(lines[i+j])
+			}
+		}
+
+		last := i + len(want) - 1
+		end := starts[last] + len(strings.TrimSuffix(lines[last], "\r"))
+		spans = append(spans, textSpan{start: starts[i], end: end, indents: indents})
+		i += len(want)
+	}
+	return spans
+}
+
+// reindent converts the indentation of replacement lines using the mapping
+// from search-text indentation to file indentation seen in the match. Unknown
+// indentation is converted by its longest mapped prefix.
+func reindent(replace string, indents map[string]string) string {
+	lines := strings.Split(replace, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := leadingSpace(line)
+		if mapped, ok := indents[indent]; ok {
+			lines[i] = mapped + line[len(indent):]
+			continue
+		}
+		best := ""
+		for from := range indents {
+			if len(from) > len(best) && strings.HasPrefix(indent, from) {
+				best = from
+			}
+		}
+		if best != "" {
+			lines[i] = indents[best] + line[len(best):]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// leadingSpace returns the indentation of a line
+func leadingSpace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+func trimTrailing(line string) string {
+	return strings.TrimRight(line, " \t\r")
+}
+
+func normalizeSpace(line string) string {
+	return strings.Join(strings.Fields(line), " ")
+}
@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestModifyFileAction_Apply(t *testing.T) {
+	content := "func a() {\n\tif ok {\n\t\treturn 1\n\t}\n}\n\nfunc b() {\n\tif ok {\n\t\treturn 1\n\t}\n}\n"
+
+	tests := []struct {
+		name    string
+		action  ModifyFileAction
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "exact unique",
+			action: ModifyFileAction{Search: "func b", Replace: "func c"},
+			want:   strings.Replace(content, "func b", "func c", 1),
+		},
+		{
+			name:    "exact ambiguous",
+			action:  ModifyFileAction{Search: "return 1", Replace: "return 2"},
+			wantErr: "ambiguous: found 2 times",
+		},
+		{
+			name:   "replace all",
+			action: ModifyFileAction{Search: "return 1", Replace: "return 2", ReplaceAll: true},
+			want:   strings.ReplaceAll(content, "return 1", "return 2"),
+		},
+		{
+			name:   "replace nth",
+			action: ModifyFileAction{Search: "return 1", Replace: "return 2", Occurrence: 2},
+			want:   "func a() {\n\tif ok {\n\t\treturn 1\n\t}\n}\n\nfunc b() {\n\tif ok {\n\t\treturn 2\n\t}\n}\n",
+		},
+		{
+			name:    "occurrence out of range",
+			action:  ModifyFileAction{Search: "return 1", Replace: "return 2", Occurrence: 3},
+			wantErr: "found 2 time(s)",
+		},
+		{
+			name:   "line match ignores trailing whitespace",
+			action: ModifyFileAction{Search: "func a() {  \n\tif ok {\t", Replace: "func a() {\n\tif !ok {", Match: MatchLine},
+			want:   strings.Replace(content, "func a() {\n\tif ok {", "func a() {\n\tif !ok {", 1),
+		},
+		{
+			name:    "line match is anchored to whole lines",
+			action:  ModifyFileAction{Search: "return", Replace: "yield", Match: MatchLine},
+			wantErr: "not found",
+		},
+		{
+			name: "whitespace match re-indents the replacement",
+			action: ModifyFileAction{
+				Search:  "func b() {\n    if ok {\n        return 1\n    }",
+				Replace: "func b() {\n    if !ok {\n        return 0\n    }",
+				Match:   MatchWhitespace,
+			},
+			want: "func a() {\n\tif ok {\n\t\treturn 1\n\t}\n}\n\nfunc b() {\n\tif !ok {\n\t\treturn 0\n\t}\n}\n",
+		},
+		{
+			name: "auto falls back to whitespace matching",
+			action: ModifyFileAction{
+				Search:  "    if ok {\n        return 1\n    }\n}\n\nfunc b() {",
+				Replace: "    if ok {\n        return 1\n    }\n}\n\nfunc c() {",
+			},
+			want: "func a() {\n\tif ok {\n\t\treturn 1\n\t}\n}\n\nfunc c() {\n\tif ok {\n\t\treturn 1\n\t}\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.action.apply(content)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Unexpected result:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActionParser_ParseModifyFileOptions(t *testing.T) {
+	parser := NewActionParser()
+
+	response := `<modify_file>
+<path>main.go</path>
+<search>
+    return 1
+</search>
+<replace>
+    return 2
+</replace>
+<match>whitespace</match>
+<occurrence>all</occurrence>
+</modify_file>`
+
+	actions := parser.Parse(response)
+	if len(actions) != 1 {
+		t.Fatalf("Expected 1 action, got %d", len(actions))
+	}
+	modify := actions[0].(*ModifyFileAction)
+	if modify.Search != "    return 1" {
+		t.Errorf("Expected indentation to be kept, got %q", modify.Search)
+	}
+	if modify.Match != MatchWhitespace || !modify.ReplaceAll {
+		t.Errorf("Unexpected options: %+v", modify)
+	}
+	if modify.String() != "MODIFY_FILE: main.go (whitespace match, all occurrences)" {
+		t.Errorf("Unexpected String(): %s", modify.String())
+	}
+}
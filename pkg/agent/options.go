@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"bufio"
+	"io"
+)
+
+// Option configures an Agent created by New
+type Option func(*Agent)
+
+// WithModel sets the model the agent talks to
+func WithModel(name string) Option {
+	return func(a *Agent) {
+		a.modelName = name
+	}
+}
+
+// WithSystemPrompt sets the system prompt sent with every request
+func WithSystemPrompt(prompt string) Option {
+	return func(a *Agent) {
+		a.systemPrompt = prompt
+	}
+}
+
+// WithWorkDir sets the directory actions run in; files outside it can't be
+// written. The default is the current directory.
+func WithWorkDir(dir string) Option {
+	return func(a *Agent) {
+		a.workDir = dir
+	}
+}
+
+// WithReadRoots allows actions to read files under the given directories in
+// addition to the working directory
+func WithReadRoots(roots ...string) Option {
+	return func(a *Agent) {
+		a.readRoots = roots
+	}
+}
+
+// WithCommandPolicy checks every command against policy before it runs
+func WithCommandPolicy(policy *CommandPolicy) Option {
+	return func(a *Agent) {
+		a.commandPolicy = policy
+	}
+}
+
+// WithCommandOptions sets how commands are run: shell mode, timeout and the
+// amount of output returned to the model
+func WithCommandOptions(opts CommandOptions) Option {
+	return func(a *Agent) {
+		a.commandOptions = opts
+	}
+}
+
+// WithAutoExecute runs the actions the model requests without waiting for
+// ExecutePendingActions
+func WithAutoExecute(enabled bool) Option {
+	return func(a *Agent) {
+		a.autoExecuteActions = enabled
+	}
+}
+
+// WithActionRegistry sets the actions the agent understands; the default is
+// DefaultActionRegistry
+func WithActionRegistry(registry *ActionRegistry) Option {
+	return func(a *Agent) {
+		a.actionParser = NewRegistryParser(registry)
+	}
+}
+
+// WithCompaction sets how the history is compacted when the prompt nears the
+// model's context window; the default is CompactionTruncate
+func WithCompaction(strategy CompactionStrategy) Option {
+	return func(a *Agent) {
+		a.compaction = strategy
+	}
+}
+
+// WithKeepRecent sets how many of the most recent exchanges compaction keeps
+// intact. Values below 1 keep the default.
+func WithKeepRecent(n int) Option {
+	return func(a *Agent) {
+		a.SetKeepRecentExchanges(n)
+	}
+}
+
+// WithOutput sets where the agent writes progress, action results and the
+// REPL. By default all of it is discarded.
+func WithOutput(w io.Writer) Option {
+	return func(a *Agent) {
+		a.out = w
+	}
+}
+
+// WithInput sets where the REPL and confirmation prompts read from; the
+// default is standard input
+func WithInput(r io.Reader) Option {
+	return func(a *Agent) {
+		a.input = bufio.NewReader(r)
+	}
+}
+
+// WithEventSink sends the agent's events to sink instead of rendering them as
+// text on the output writer
+func WithEventSink(sink EventSink) Option {
+	return func(a *Agent) {
+		a.events = sink
+	}
+}
@@ -0,0 +1,504 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxPatchFuzz is the number of context lines that may be ignored at each end
+// of a hunk when it doesn't match exactly
+const maxPatchFuzz = 2
+
+// hunkHeaderRegex matches "@@ -l,s +l,s @@"; counts are optional
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ApplyPatchAction applies a unified diff to one or more files. Hunks are
+// located near their stated line numbers, tolerating offsets, whitespace
+// differences and (as a last resort) mismatched outer context lines. If any
+// hunk fails, no file is changed.
+type ApplyPatchAction struct {
+	// Path overrides the file named in the diff headers; it is required when
+	// the diff has no headers
+	Path  string `json:"path,omitempty"`
+	Patch string `json:"patch"`
+
+	// Populated by Execute
+	report  []string
+	written int
+}
+
+// filePatch is the part of a patch that applies to one file
+type filePatch struct {
+	oldPath  string // empty for new files
+	newPath  string // empty for deleted files
+	noHeader bool   // the patch had no ---/+++ lines
+	hunks    []patchHunk
+}
+
+// patchHunk is one "@@" section of a file patch
+type patchHunk struct {
+	header   string
+	oldStart int
+	oldCount int
+	lines    []patchLine
+	noEOL    bool // the new side of the hunk ends without a newline
+}
+
+// patchLine is one line of a hunk: ' ' context, '-' removed or '+' added
+type patchLine struct {
+	kind byte
+	text string
+}
+
+func (a *ApplyPatchAction) Execute(ctx context.Context, workDir string) error {
+	files, err := a.files()
+	if err != nil {
+		return err
+	}
+
+	// Compute every result before writing anything
+	type result struct {
+		fullPath string
+		content  string
+		remove   bool
+	}
+	var results []result
+	var errs []error
+	a.report = nil
+	a.written = 0
+
+	for _, fp := range files {
+		name := fp.target()
+		fullPath, err := resolvePath(ctx, workDir, name, true)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		var original string
+		existing, err := os.ReadFile(fullPath)
+		switch {
+		case err == nil && fp.oldPath == "":
+			errs = append(errs, fmt.Errorf("%s: file already exists", name))
+			continue
+		case err == nil:
+			original = string(existing)
+		case os.IsNotExist(err) && fp.oldPath == "":
+			// New file
+		default:
+			errs = append(errs, fmt.Errorf("%s: failed to read file: %w", name, err))
+			continue
+		}
+
+		patched, notes, hunkErrs := applyHunks(original, fp.hunks)
+		if len(hunkErrs) > 0 {
+			for _, err := range hunkErrs {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+			continue
+		}
+
+		if fp.newPath == "" {
+			results = append(results, result{fullPath: fullPath, remove: true})
+			a.report = append(a.report, fmt.Sprintf("%s: deleted", name))
+			continue
+		}
+		results = append(results, result{fullPath: fullPath, content: patched})
+		summary := fmt.Sprintf("%s: %d hunk(s) applied", name, len(fp.hunks))
+		if len(notes) > 0 {
+			summary += " (" + strings.Join(notes, "; ") + ")"
+		}
+		a.report = append(a.report, summary)
+	}
+
+	if len(errs) > 0 {
+		a.report = nil
+		return fmt.Errorf("patch not applied: %w", errors.Join(errs...))
+	}
+
+	for _, r := range results {
+		if r.remove {
+			if err := os.Remove(r.fullPath); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", r.fullPath, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(r.fullPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(r.fullPath), err)
+		}
+		if err := os.WriteFile(r.fullPath, []byte(r.content), 0644); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", r.fullPath, err)
+		}
+		a.written += len(r.content)
+	}
+
+	return nil
+}
+
+func (a *ApplyPatchAction) bytesWritten() int {
+	return a.written
+}
+
+// Output describes what was patched, including hunks that needed an offset or
+// fuzzy matching
+func (a *ApplyPatchAction) Output() string {
+	return strings.Join(a.report, "\n")
+}
+
+func (a *ApplyPatchAction) Validate() error {
+	if strings.TrimSpace(a.Patch) == "" {
+		return fmt.Errorf("patch cannot be empty")
+	}
+	files, err := a.files()
+	if err != nil {
+		return err
+	}
+	for _, fp := range files {
+		if err := validateActionPath(fp.target()); err != nil {
+			return fmt.Errorf("invalid file path: %w", err)
+		}
+	}
+	return nil
+}
+
+func (a *ApplyPatchAction) String() string {
+	files, err := a.files()
+	if err != nil {
+		return "APPLY_PATCH: (invalid patch)"
+	}
+	var names []string
+	hunks := 0
+	for _, fp := range files {
+		names = append(names, fp.target())
+		hunks += len(fp.hunks)
+	}
+	return fmt.Sprintf("APPLY_PATCH: %s (%d hunk(s))", strings.Join(names, ", "), hunks)
+}
+
+func (a *ApplyPatchAction) ActionName() string {
+	return "apply_patch"
+}
+
+func (a *ApplyPatchAction) targetPaths() []string {
+	files, err := a.files()
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	for _, fp := range files {
+		paths = append(paths, fp.target())
+	}
+	return paths
+}
+
+// files parses the patch and applies the path override
+func (a *ApplyPatchAction) files() ([]filePatch, error) {
+	files, err := parsePatch(a.Patch)
+	if err != nil {
+		return nil, err
+	}
+	if a.Path != "" {
+		if len(files) > 1 {
+			return nil, fmt.Errorf("a path can only be given for single-file patches")
+		}
+		fp := &files[0]
+		switch {
+		case fp.noHeader:
+			fp.oldPath, fp.newPath = a.Path, a.Path
+		case fp.oldPath == "":
+			fp.newPath = a.Path
+		case fp.newPath == "":
+			fp.oldPath = a.Path
+		default:
+			fp.oldPath, fp.newPath = a.Path, a.Path
+		}
+	}
+	for _, fp := range files {
+		if fp.target() == "" {
+			return nil, fmt.Errorf("patch has no file name; give a path")
+		}
+	}
+	return files, nil
+}
+
+// target returns the path a file patch writes to (or deletes)
+func (fp *filePatch) target() string {
+	if fp.newPath != "" {
+		return fp.newPath
+	}
+	return fp.oldPath
+}
+
+// parsePatch splits a unified diff into file patches. Hunk line counts are
+// not trusted: a hunk runs until the next hunk or file header. Headers may be
+// omitted entirely for a single-file patch.
+func parsePatch(text string) ([]filePatch, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+
+	var files []filePatch
+	var current *filePatch
+	var hunk *patchHunk
+
+	finishHunk := func() {
+		if hunk != nil {
+			// Trailing blank lines are usually formatting, not context
+			for len(hunk.lines) > 0 && hunk.lines[len(hunk.lines)-1] == (patchLine{kind: ' '}) {
+				hunk.lines = hunk.lines[:len(hunk.lines)-1]
+			}
+			current.hunks = append(current.hunks, *hunk)
+			hunk = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if current != nil {
+				finishHunk()
+			}
+			files = append(files, filePatch{
+				oldPath: patchPath(line[4:], "a/"),
+				newPath: patchPath(lines[i+1][4:], "b/"),
+			})
+			current = &files[len(files)-1]
+			i++
+
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				files = append(files, filePatch{noHeader: true})
+				current = &files[len(files)-1]
+			}
+			finishHunk()
+			m := hunkHeaderRegex.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid hunk header: %s", line)
+			}
+			hunk = &patchHunk{header: m[0]}
+			hunk.oldStart, _ = strconv.Atoi(m[1])
+			hunk.oldCount = 1
+			if m[2] != "" {
+				hunk.oldCount, _ = strconv.Atoi(m[2])
+			}
+
+		case hunk != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" applies to the previous line
+			if n := len(hunk.lines); n > 0 && hunk.lines[n-1].kind != '-' {
+				hunk.noEOL = true
+			}
+
+		case hunk != nil && line == "":
+			// Models often drop the space of empty context lines
+			hunk.lines = append(hunk.lines, patchLine{kind: ' '})
+
+		case hunk != nil && (line[0] == ' ' || line[0] == '-' || line[0] == '+'):
+			hunk.lines = append(hunk.lines, patchLine{kind: line[0], text: line[1:]})
+
+		default:
+			// "diff --git", "index ..." and commentary between files
+			if current != nil {
+				finishHunk()
+			}
+		}
+	}
+	if current != nil {
+		finishHunk()
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no hunks found in patch")
+	}
+	for _, fp := range files {
+		if len(fp.hunks) == 0 {
+			return nil, fmt.Errorf("no hunks found for %s", fp.target())
+		}
+	}
+	return files, nil
+}
+
+// patchPath extracts a file path from a ---/+++ header, returning "" for
+// /dev/null
+func patchPath(header, prefix string) string {
+	// Drop a trailing timestamp, separated by a tab
+	if i := strings.IndexByte(header, '\t'); i >= 0 {
+		header = header[:i]
+	}
+	header = strings.TrimSpace(header)
+	if header == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(header, prefix)
+}
+
+// applyHunks applies hunks to content in order. It returns the new content,
+// notes about hunks that needed an offset or fuzz, and one error per hunk that
+// could not be placed.
+func applyHunks(content string, hunks []patchHunk) (string, []string, []error) {
+	crlf := strings.Contains(content, "\r\n")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	lines, noEOL := splitDiffLines(content)
+	if content == "" {
+		noEOL = false
+	}
+
+	var notes []string
+	var errs []error
+	delta := 0  // lines added minus removed by earlier hunks
+	minPos := 0 // hunks may not overlap earlier ones
+
+	for i, h := range hunks {
+		expected := h.oldStart - 1
+		if h.oldCount == 0 {
+			expected = h.oldStart
+		}
+		expected += delta
+
+		pos, used, fuzz, level, ok := locateHunk(lines, h.lines, expected, minPos)
+		if !ok {
+			errs = append(errs, fmt.Errorf("hunk #%d (%s) failed: context not found", i+1, h.header))
+			continue
+		}
+
+		// Build the replacement, keeping the file's own context lines
+		var replacement []string
+		cursor := pos
+		for _, pl := range used {
+			switch pl.kind {
+			case ' ':
+				replacement = append(replacement, lines[cursor])
+				cursor++
+			case '-':
+				cursor++
+			case '+':
+				replacement = append(replacement, pl.text)
+			}
+		}
+
+		atEnd := cursor == len(lines)
+		lines = append(lines[:pos], append(replacement, lines[cursor:]...)...)
+		if atEnd {
+			noEOL = h.noEOL
+		}
+
+		if offset := pos - expected; offset != 0 || fuzz > 0 || level > 0 {
+			note := fmt.Sprintf("hunk #%d at line %d", i+1, pos+1)
+			if offset != 0 {
+				note += fmt.Sprintf(", offset %+d", offset)
+			}
+			if level > 0 {
+				note += ", whitespace ignored"
+			}
+			if fuzz > 0 {
+				note += fmt.Sprintf(", fuzz %d", fuzz)
+			}
+			notes = append(notes, note)
+		}
+
+		delta += len(replacement) - (cursor - pos)
+		minPos = pos + len(replacement)
+	}
+
+	result := strings.Join(lines, "\n")
+	if len(lines) > 0 && !noEOL {
+		result += "\n"
+	}
+	if crlf {
+		result = strings.ReplaceAll(result, "\n", "\r\n")
+	}
+	return result, notes, errs
+}
+
+// locateHunk finds where a hunk's old lines occur in the file, searching
+// outward from the expected position. It tries exact matches first, then
+// ignores trailing whitespace, then all whitespace differences, then drops up
+// to maxPatchFuzz outer context lines. It returns the position, the hunk lines
+// actually matched, the fuzz and whitespace level used, and whether a match
+// was found.
+func locateHunk(lines []string, hunk []patchLine, expected, minPos int) (int, []patchLine, int, int, bool) {
+	for fuzz := 0; fuzz <= maxPatchFuzz; fuzz++ {
+		used, dropped := trimContext(hunk, fuzz)
+		if fuzz > 0 && len(used) == len(hunk) {
+			continue
+		}
+
+		var old []string
+		for _, pl := range used {
+			if pl.kind != '+' {
+				old = append(old, pl.text)
+			}
+		}
+
+		for level := 0; level <= 2; level++ {
+			if pos, ok := searchLines(lines, old, expected+dropped, minPos, level); ok {
+				return pos, used, fuzz, level, true
+			}
+		}
+	}
+	return 0, nil, 0, 0, false
+}
+
+// trimContext drops up to n context lines from each end of a hunk, always
+// keeping at least one, and returns the remaining lines and the number dropped
+// from the start
+func trimContext(hunk []patchLine, n int) ([]patchLine, int) {
+	leading := 0
+	for leading < len(hunk) && hunk[leading].kind == ' ' {
+		leading++
+	}
+	trailing := 0
+	for trailing < len(hunk)-leading && hunk[len(hunk)-1-trailing].kind == ' ' {
+		trailing++
+	}
+
+	start := min(n, max(leading-1, 0))
+	end := len(hunk) - min(n, max(trailing-1, 0))
+	return hunk[start:end], start
+}
+
+// searchLines returns the position of old in lines nearest to expected, at or
+// after minPos. level 0 compares exactly, 1 ignores trailing whitespace and 2
+// ignores all whitespace differences.
+func searchLines(lines, old []string, expected, minPos, level int) (int, bool) {
+	last := len(lines) - len(old)
+	if last < minPos {
+		return 0, false
+	}
+	expected = min(max(expected, minPos), last)
+
+	for d := 0; ; d++ {
+		before, after := expected-d, expected+d
+		if before < minPos && after > last {
+			return 0, false
+		}
+		if before >= minPos && linesMatch(lines[before:before+len(old)], old, level) {
+			return before, true
+		}
+		if d > 0 && after <= last && linesMatch(lines[after:after+len(old)], old, level) {
+			return after, true
+		}
+	}
+}
+
+// linesMatch compares two equally long line slices at a whitespace level
+func linesMatch(a, b []string, level int) bool {
+	for i := range b {
+		x, y := a[i], b[i]
+		switch level {
+		case 1:
+			x, y = strings.TrimRight(x, " \t\r"), strings.TrimRight(y, " \t\r")
+		case 2:
+			x, y = strings.Join(strings.Fields(x), " "), strings.Join(strings.Fields(y), " ")
+		}
+		if x != y {
+			return false
+		}
+	}
+	return true
+}
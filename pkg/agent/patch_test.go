@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const patchTestFile = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Println("hello")
+}
+
+func helper() int {
+	return 1
+}
+`
+
+func TestApplyPatchAction_Execute(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "main.go")
+	if err := os.WriteFile(path, []byte(patchTestFile), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Two hunks, the second with a wrong line number
+	patch := `--- a/main.go
++++ b/main.go
+@@ -5,3 +5,3 @@
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("hello, world")
+ }
+@@ -20,3 +20,3 @@
+ func helper() int {
+-	return 1
++	return 2
+ }
+`
+	action := &ApplyPatchAction{Patch: patch}
+	if err := action.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if err := action.Execute(context.Background(), tmpDir); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	want := strings.Replace(strings.Replace(patchTestFile, `"hello"`, `"hello, world"`, 1), "return 1", "return 2", 1)
+	if string(content) != want {
+		t.Errorf("Unexpected content:\n%s", string(content))
+	}
+	if !strings.Contains(action.Output(), "hunk #2 at line 9, offset -11") {
+		t.Errorf("Expected the offset to be reported, got: %s", action.Output())
+	}
+}
+
+func TestApplyPatchAction_Fuzzy(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "main.go")
+	if err := os.WriteFile(path, []byte(patchTestFile), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Spaces instead of tabs, no file headers, and a stale outer context line
+	patch := `@@ -5,4 +5,4 @@
+ func main() {
+-    fmt.Println("hello")
++    fmt.Println("hi")
+ }
+ // stale context
+`
+	action := &ApplyPatchAction{Path: "main.go", Patch: patch}
+	if err := action.Execute(context.Background(), tmpDir); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	// Context lines keep the file's own whitespace
+	content, _ := os.ReadFile(path)
+	if !strings.Contains(string(content), "    fmt.Println(\"hi\")\n}") || !strings.Contains(string(content), "\nfunc main() {\n") {
+		t.Errorf("Unexpected content:\n%s", string(content))
+	}
+	if !strings.Contains(action.Output(), "fuzz 1") {
+		t.Errorf("Expected fuzz to be reported, got: %s", action.Output())
+	}
+}
+
+func TestApplyPatchAction_HunkFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "main.go")
+	if err := os.WriteFile(path, []byte(patchTestFile), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	patch := `--- a/main.go
++++ b/main.go
+@@ -5,3 +5,3 @@
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("bye")
+ }
+@@ -9,3 +9,3 @@
+ func missing() {
+-	return 1
++	return 2
+ }
+`
+	err := (&ApplyPatchAction{Patch: patch}).Execute(context.Background(), tmpDir)
+	if err == nil || !strings.Contains(err.Error(), "hunk #2") || strings.Contains(err.Error(), "hunk #1") {
+		t.Fatalf("Expected only hunk #2 to fail, got %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != patchTestFile {
+		t.Error("Expected the file to be left unchanged when a hunk fails")
+	}
+}
+
+func TestApplyPatchAction_CreateAndDelete(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "old.txt"), []byte("bye\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	patch := `diff --git a/new.txt b/new.txt
+--- /dev/null
++++ b/docs/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+	action := &ApplyPatchAction{Patch: patch}
+	if got := action.String(); got != "APPLY_PATCH: docs/new.txt, old.txt (2 hunk(s))" {
+		t.Errorf("Unexpected String(): %s", got)
+	}
+	if err := action.Execute(context.Background(), tmpDir); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "docs", "new.txt"))
+	if err != nil || string(content) != "hello\nworld\n" {
+		t.Errorf("Expected new file to be created, got %q (err=%v)", string(content), err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected old.txt to be deleted, got err=%v", err)
+	}
+}
+
+func TestActionParser_ParseApplyPatch(t *testing.T) {
+	parser := NewActionParser()
+
+	response := "I'll patch it:\n\n<apply_patch>\n--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,2 @@\n package main\n-// old\n+// new\n</apply_patch>\n\n" +
+		"<apply_patch>\n<path>util.go</path>\n<patch>\n@@ -1 +1 @@\n-a\n+b\n</patch>\n</apply_patch>"
+
+	actions := parser.Parse(response)
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %d", len(actions))
+	}
+
+	first, ok := actions[0].(*ApplyPatchAction)
+	if !ok {
+		t.Fatalf("Expected ApplyPatchAction, got %T", actions[0])
+	}
+	if !strings.HasPrefix(first.Patch, "--- a/main.go") || !strings.Contains(first.Patch, "\n package main\n") {
+		t.Errorf("Unexpected patch: %q", first.Patch)
+	}
+
+	second := actions[1].(*ApplyPatchAction)
+	if second.Path != "util.go" || second.Patch != "@@ -1 +1 @@\n-a\n+b" {
+		t.Errorf("Unexpected second action: %+v", second)
+	}
+	if err := second.Validate(); err != nil {
+		t.Errorf("Expected headerless patch with a path to validate, got %v", err)
+	}
+}
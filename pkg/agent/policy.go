@@ -0,0 +1,307 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PolicyDecision is what a command policy decides for a command
+type PolicyDecision string
+
+const (
+	// PolicyAllow runs the command without asking
+	PolicyAllow PolicyDecision = "allow"
+	// PolicyDeny refuses to run the command
+	PolicyDeny PolicyDecision = "deny"
+	// PolicyAsk asks the user, who may approve the command for the rest of
+	// the session
+	PolicyAsk PolicyDecision = "ask"
+	// PolicyAlwaysAsk asks the user every time the command runs
+	PolicyAlwaysAsk PolicyDecision = "always_ask"
+)
+
+// CommandApproval is the user's answer when a policy asks about a command
+type CommandApproval int
+
+const (
+	// ApprovalDeny refuses the command
+	ApprovalDeny CommandApproval = iota
+	// ApprovalOnce runs the command this time only
+	ApprovalOnce
+	// ApprovalSession runs the command and remembers the approval for the
+	// rest of the session
+	ApprovalSession
+)
+
+// AskFunc asks the user whether a command may run
+type AskFunc func(command, reason string) CommandApproval
+
+// PolicyRule matches commands by binary and arguments. Binary is a glob
+// matched against the program name (or its full path when the pattern
+// contains a '/'); Args is a regular expression matched against the
+// space-joined arguments. Empty fields match anything.
+type PolicyRule struct {
+	Binary   string         `json:"binary,omitempty"`
+	Args     string         `json:"args,omitempty"`
+	Decision PolicyDecision `json:"decision"`
+	Reason   string         `json:"reason,omitempty"`
+
+	argsRegex *regexp.Regexp
+}
+
+// policyFile is the on-disk form of a command policy
+type policyFile struct {
+	Default PolicyDecision `json:"default,omitempty"`
+	Rules   []PolicyRule   `json:"rules"`
+}
+
+// CommandPolicy decides whether commands emitted by the model may run. Rules
+// are checked in order and the first match wins; commands that match no rule
+// get the default decision.
+type CommandPolicy struct {
+	defaultDecision PolicyDecision
+	rules           []PolicyRule
+	approved        map[string]bool
+}
+
+// NewCommandPolicy creates a policy with no rules and the given default
+func NewCommandPolicy(defaultDecision PolicyDecision) *CommandPolicy {
+	return &CommandPolicy{
+		defaultDecision: defaultDecision,
+		approved:        make(map[string]bool),
+	}
+}
+
+// DefaultPolicyPath returns the path of the command policy used when none is
+// given explicitly
+func DefaultPolicyPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "llmapi", "policy.json")
+}
+
+// LoadCommandPolicy reads a command policy from a JSON file. A file without a
+// default decision asks about unmatched commands.
+func LoadCommandPolicy(path string) (*CommandPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+
+	var file policyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode policy: %w", err)
+	}
+
+	if file.Default == "" {
+		file.Default = PolicyAsk
+	}
+	if err := validateDecision(file.Default); err != nil {
+		return nil, fmt.Errorf("invalid default: %w", err)
+	}
+
+	policy := NewCommandPolicy(file.Default)
+	for i, rule := range file.Rules {
+		if err := policy.AddRule(rule); err != nil {
+			return nil, fmt.Errorf("invalid rule %d: %w", i+1, err)
+		}
+	}
+	return policy, nil
+}
+
+// AddRule appends a rule to the policy
+func (p *CommandPolicy) AddRule(rule PolicyRule) error {
+	if err := validateDecision(rule.Decision); err != nil {
+		return err
+	}
+	if _, err := filepath.Match(rule.Binary, ""); err != nil {
+		return fmt.Errorf("invalid binary pattern %q: %w", rule.Binary, err)
+	}
+	if rule.Args != "" {
+		re, err := regexp.Compile(rule.Args)
+		if err != nil {
+			return fmt.Errorf("invalid args pattern %q: %w", rule.Args, err)
+		}
+		rule.argsRegex = re
+	}
+	p.rules = append(p.rules, rule)
+	return nil
+}
+
+// Rules returns the policy's rules in the order they are checked
+func (p *CommandPolicy) Rules() []PolicyRule {
+	return p.rules
+}
+
+// Default returns the decision for commands that match no rule
+func (p *CommandPolicy) Default() PolicyDecision {
+	return p.defaultDecision
+}
+
+// Approved returns the commands approved for the rest of the session
+func (p *CommandPolicy) Approved() []string {
+	commands := make([]string, 0, len(p.approved))
+	for command := range p.approved {
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// Evaluate returns the decision for a command and the reason given by the
+// matching rule. Each command of a pipeline, list, subshell or group is
+// checked and the most restrictive decision wins. Command substitutions,
+// programs named by expansions and compound commands the parser doesn't
+// follow can't be checked, so commands containing them are never allowed
+// without asking.
+func (p *CommandPolicy) Evaluate(command string) (PolicyDecision, string) {
+	line, err := parseCommandLine(command)
+	if err != nil {
+		return PolicyDeny, err.Error()
+	}
+	if len(line.segments) == 0 {
+		return PolicyDeny, "empty command"
+	}
+
+	decision, reason := PolicyAllow, ""
+	unchecked := ""
+	if line.substitution {
+		unchecked = "contains command substitution"
+	}
+	for i, words := range line.segments {
+		words, problem := simpleCommand(words, line.expanded[i])
+		if problem != "" && unchecked == "" {
+			unchecked = problem
+		}
+		if len(words) == 0 {
+			continue
+		}
+		d, r := p.evaluateSimple(words)
+		if decisionRank[d] > decisionRank[decision] {
+			decision, reason = d, r
+		}
+	}
+
+	if unchecked != "" && decisionRank[decision] < decisionRank[PolicyAsk] {
+		decision, reason = PolicyAsk, unchecked
+	}
+	return decision, reason
+}
+
+// transparentWords are reserved words and prefixes followed by a command,
+// which is checked in their place
+var transparentWords = map[string]bool{
+	"!": true, "{": true, "}": true, "if": true, "then": true, "elif": true, "else": true,
+	"fi": true, "while": true, "until": true, "do": true, "done": true, "time": true,
+}
+
+// compoundWords start compound commands whose words aren't all commands
+var compoundWords = map[string]bool{
+	"for": true, "case": true, "esac": true, "select": true, "function": true, "coproc": true,
+}
+
+// assignmentRegex matches a variable assignment preceding a command
+var assignmentRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// simpleCommand strips the reserved words and variable assignments in front
+// of a segment's program. It returns the program and its arguments, which are
+// empty if the segment runs nothing, and a description of any syntax that
+// keeps the program from being known.
+func simpleCommand(words []string, expanded []bool) ([]string, string) {
+	for i, word := range words {
+		switch {
+		case transparentWords[word] || assignmentRegex.MatchString(word):
+			continue
+		case compoundWords[word]:
+			return nil, fmt.Sprintf("uses shell syntax the policy can't check (%s)", word)
+		case expanded[i]:
+			return nil, fmt.Sprintf("program name %s is expanded by the shell", word)
+		}
+		return words[i:], ""
+	}
+	return nil, ""
+}
+
+// decisionRank orders decisions from least to most restrictive
+var decisionRank = map[PolicyDecision]int{
+	PolicyAllow:     0,
+	PolicyAsk:       1,
+	PolicyAlwaysAsk: 2,
+	PolicyDeny:      3,
+}
+
+// evaluateSimple returns the decision for a single program and its arguments
+func (p *CommandPolicy) evaluateSimple(words []string) (PolicyDecision, string) {
+	for _, rule := range p.rules {
+		if rule.matches(words[0], strings.Join(words[1:], " ")) {
+			return rule.Decision, rule.Reason
+		}
+	}
+	return p.defaultDecision, ""
+}
+
+// Check returns nil if the command may run. Commands the policy asks about
+// are passed to ask; without an ask function they are refused.
+func (p *CommandPolicy) Check(command string, ask AskFunc) error {
+	decision, reason := p.Evaluate(command)
+
+	switch decision {
+	case PolicyAllow:
+		return nil
+	case PolicyDeny:
+		if reason == "" {
+			return fmt.Errorf("command denied by policy")
+		}
+		return fmt.Errorf("command denied by policy: %s", reason)
+	}
+
+	if decision == PolicyAsk && p.approved[command] {
+		return nil
+	}
+	if ask == nil {
+		return fmt.Errorf("command requires approval")
+	}
+
+	switch ask(command, reason) {
+	case ApprovalSession:
+		if decision == PolicyAsk {
+			p.approved[command] = true
+		}
+		return nil
+	case ApprovalOnce:
+		return nil
+	default:
+		return fmt.Errorf("command denied by user")
+	}
+}
+
+// matches reports whether the rule applies to a program and its arguments
+func (r *PolicyRule) matches(program, args string) bool {
+	if r.Binary != "" {
+		name := program
+		if !strings.Contains(r.Binary, "/") {
+			name = filepath.Base(program)
+		}
+		if ok, _ := filepath.Match(r.Binary, name); !ok {
+			return false
+		}
+	}
+	if r.argsRegex != nil && !r.argsRegex.MatchString(args) {
+		return false
+	}
+	return true
+}
+
+// validateDecision checks that a decision is one of the known values
+func validateDecision(decision PolicyDecision) error {
+	switch decision {
+	case PolicyAllow, PolicyDeny, PolicyAsk, PolicyAlwaysAsk:
+		return nil
+	}
+	return fmt.Errorf("unknown decision %q (use allow, deny, ask or always_ask)", decision)
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCommandPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	config := `{
+		"default": "deny",
+		"rules": [
+			{"binary": "rm", "args": "-[a-z]*r", "decision": "deny", "reason": "recursive delete"},
+			{"binary": "go", "args": "^(build|test|vet)\\b", "decision": "allow"},
+			{"binary": "git", "args": "^push", "decision": "always_ask"},
+			{"binary": "git", "decision": "ask"},
+			{"binary": "/usr/bin/*", "decision": "allow"}
+		]
+	}`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	policy, err := LoadCommandPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadCommandPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		command string
+		want    PolicyDecision
+	}{
+		{"rm -rf /", PolicyDeny},
+		{"rm file.txt", PolicyDeny},
+		{"go build ./...", PolicyAllow},
+		{"/usr/local/go/bin/go test ./...", PolicyAllow},
+		{"go run main.go", PolicyDeny},
+		{"git push origin main", PolicyAlwaysAsk},
+		{"git status", PolicyAsk},
+		{"/usr/bin/ls -la", PolicyAllow},
+		{"ls -la", PolicyDeny},
+	}
+
+	for _, tt := range tests {
+		if got, _ := policy.Evaluate(tt.command); got != tt.want {
+			t.Errorf("Evaluate(%q) = %s, want %s", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestLoadCommandPolicy_Invalid(t *testing.T) {
+	dir := t.TempDir()
+
+	configs := map[string]string{
+		"decision.json": `{"rules": [{"binary": "ls", "decision": "maybe"}]}`,
+		"regex.json":    `{"rules": [{"args": "(", "decision": "deny"}]}`,
+		"default.json":  `{"default": "sometimes"}`,
+	}
+	for name, config := range configs {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatalf("Failed to write policy: %v", err)
+		}
+		if _, err := LoadCommandPolicy(path); err == nil {
+			t.Errorf("Expected an error loading %s", name)
+		}
+	}
+}
+
+func TestCommandPolicy_Check(t *testing.T) {
+	policy := NewCommandPolicy(PolicyAsk)
+	policy.AddRule(PolicyRule{Binary: "make", Args: "^deploy", Decision: PolicyAlwaysAsk})
+
+	asked := 0
+	approve := func(answer CommandApproval) AskFunc {
+		return func(command, reason string) CommandApproval {
+			asked++
+			return answer
+		}
+	}
+
+	if err := policy.Check("ls", nil); err == nil {
+		t.Error("Expected commands needing approval to be refused without an ask function")
+	}
+	if err := policy.Check("ls", approve(ApprovalDeny)); err == nil {
+		t.Error("Expected a denied command to return an error")
+	}
+
+	// A session approval is remembered for "ask" rules...
+	if err := policy.Check("ls", approve(ApprovalSession)); err != nil {
+		t.Fatalf("Expected approved command to pass, got %v", err)
+	}
+	asked = 0
+	if err := policy.Check("ls", approve(ApprovalDeny)); err != nil || asked != 0 {
+		t.Errorf("Expected session approval to be remembered, err=%v asked=%d", err, asked)
+	}
+
+	// ...but not for "always_ask" rules
+	if err := policy.Check("make deploy", approve(ApprovalSession)); err != nil {
+		t.Fatalf("Expected approved command to pass, got %v", err)
+	}
+	asked = 0
+	if err := policy.Check("make deploy", approve(ApprovalOnce)); err != nil || asked != 1 {
+		t.Errorf("Expected always_ask command to be asked again, err=%v asked=%d", err, asked)
+	}
+}
+
+func TestExecutor_CommandPolicyDenial(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	policy := NewCommandPolicy(PolicyAllow)
+	policy.AddRule(PolicyRule{Binary: "touch", Decision: PolicyDeny, Reason: "no touching"})
+
+	executor := NewExecutor(tmpDir)
+	executor.SetCommandPolicy(policy, nil)
+
+	action := &ExecuteCommandAction{Command: "touch denied.txt"}
+	results := executor.run(context.Background(), []Action{action})
+	if results[0].Err == nil {
+		t.Fatal("Expected the command to be denied")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "denied.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected denied command not to run, got err=%v", err)
+	}
+
+	text := FormatResults(results)
+	if !strings.Contains(text, "no touching") {
+		t.Errorf("Expected the denial reason in the results, got:\n%s", text)
+	}
+}
+
+func TestCommandPolicy_EvaluateShellSyntax(t *testing.T) {
+	policy := NewCommandPolicy(PolicyAllow)
+	policy.AddRule(PolicyRule{Binary: "rm", Decision: PolicyDeny, Reason: "no deleting"})
+
+	tests := []struct {
+		command string
+		want    PolicyDecision
+	}{
+		{"go build ./...", PolicyAllow},
+		{"go build ./...\n", PolicyAllow},
+		{"go build\nrm -rf ~", PolicyDeny},
+		{"go build ./... ; (rm -rf ~)", PolicyDeny},
+		{"{ rm -rf ~; }", PolicyDeny},
+		{"if true; then rm -rf ~; fi", PolicyDeny},
+		{"! rm -rf ~", PolicyDeny},
+		{"FOO=bar rm -rf ~", PolicyDeny},
+		{"echo `rm -rf ~`", PolicyDeny},
+		{"echo $(rm -rf ~)", PolicyDeny},
+		{"echo \"$(pwd)\"", PolicyAsk},
+		{"$CMD -rf ~", PolicyAsk},
+		{"/bin/r? -rf ~", PolicyAsk},
+		{"for f in *.go; do gofmt -l $f; done", PolicyAsk},
+		{"echo '(rm -rf ~)'", PolicyAllow},
+	}
+	for _, tt := range tests {
+		if got, reason := policy.Evaluate(tt.command); got != tt.want {
+			t.Errorf("Evaluate(%q) = %s (%s), want %s", tt.command, got, reason, tt.want)
+		}
+	}
+}
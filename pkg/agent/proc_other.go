@@ -0,0 +1,9 @@
+//go:build !unix
+
+package agent
+
+import "os/exec"
+
+// killProcessGroup is a no-op where process groups aren't available; only
+// the command itself is killed when it is cancelled
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// puller is implemented by providers that can download models, which the
+// /pull command uses
+type puller interface {
+	StreamPullModelWithContext(ctx context.Context, req *ollama.PullModelRequest, onProgress ollama.ProgressFunc) error
+}
+
+// PullModel downloads a model from the backend's registry, reporting each
+// status line to onProgress. Only Ollama backends can pull models.
+func (a *Agent) PullModel(ctx context.Context, name string, onProgress ollama.ProgressFunc) error {
+	p, ok := a.client.(puller)
+	if !ok {
+		return fmt.Errorf("this provider cannot pull models")
+	}
+	if err := p.StreamPullModelWithContext(ctx, &ollama.PullModelRequest{Name: name}, onProgress); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", name, err)
+	}
+	return nil
+}
+
+// progressBarWidth is the number of cells in a progress bar
+const progressBarWidth = 30
+
+// ProgressBar renders the progress of a model pull, push or create on a
+// terminal: a bar, redrawn in place, for each layer being transferred and a
+// line for every other step.
+type ProgressBar struct {
+	w       io.Writer
+	status  string // last status printed
+	digest  string // layer of the bar being drawn
+	drawing bool   // whether the cursor is at the end of an unfinished bar
+}
+
+// NewProgressBar creates a progress bar that draws on w
+func NewProgressBar(w io.Writer) *ProgressBar {
+	return &ProgressBar{w: w}
+}
+
+// Update draws a status line; it can be passed as an ollama.ProgressFunc
+func (b *ProgressBar) Update(p ollama.ProgressResponse) error {
+	if p.Total > 0 {
+		if b.drawing && p.Digest != b.digest {
+			fmt.Fprintln(b.w)
+		}
+		b.digest = p.Digest
+		b.status = p.Status
+		b.drawing = true
+
+		completed := p.Completed
+		if completed > p.Total {
+			completed = p.Total
+		}
+		filled := int(completed * progressBarWidth / p.Total)
+		fmt.Fprintf(b.w, "\r%s [%s%s] %3d%% %s/%s  ", p.Status,
+			strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+			completed*100/p.Total, formatBytes(completed), formatBytes(p.Total))
+		return nil
+	}
+
+	// Steps without a transfer are printed once each
+	if p.Status == b.status && !b.drawing {
+		return nil
+	}
+	b.Finish()
+	b.status = p.Status
+	fmt.Fprintln(b.w, p.Status)
+	return nil
+}
+
+// Finish ends the bar being drawn, if any, so that the next output starts on
+// a new line
+func (b *ProgressBar) Finish() {
+	if b.drawing {
+		fmt.Fprintln(b.w)
+		b.drawing = false
+	}
+}
+
+// formatBytes formats a byte count for people, e.g. "1.5 GB"
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+	"github.com/aykay76/llmapi/pkg/openai"
+)
+
+func TestProgressBar(t *testing.T) {
+	var out bytes.Buffer
+	bar := NewProgressBar(&out)
+	for _, p := range []ollama.ProgressResponse{
+		{Status: "pulling manifest"},
+		{Status: "pulling aaa", Digest: "sha256:aaa", Total: 2000000000, Completed: 500000000},
+		{Status: "pulling aaa", Digest: "sha256:aaa", Total: 2000000000, Completed: 2000000000},
+		{Status: "pulling bbb", Digest: "sha256:bbb", Total: 100, Completed: 100},
+		{Status: "verifying sha256 digest"},
+		{Status: "verifying sha256 digest"},
+		{Status: "success"},
+	} {
+		bar.Update(p)
+	}
+	bar.Finish()
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	want := []string{
+		"pulling manifest",
+		"\rpulling aaa [=======                       ]  25% 500.0 MB/2.0 GB  " +
+			"\rpulling aaa [==============================] 100% 2.0 GB/2.0 GB  ",
+		"\rpulling bbb [==============================] 100% 100 B/100 B  ",
+		"verifying sha256 digest",
+		"success",
+	}
+	if fmt.Sprintf("%q", lines) != fmt.Sprintf("%q", want) {
+		t.Errorf("Unexpected output:\n%q\nwant:\n%q", lines, want)
+	}
+}
+
+func TestPullCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/pull" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, `{"status":"pulling aaa","digest":"sha256:aaa","total":10,"completed":10}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	agent := New(ollama.NewClient(server.URL), WithModel("m"), WithOutput(&out))
+	if err := agent.handleCommand(context.Background(), "/pull llama3"); err != nil {
+		t.Fatalf("/pull failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "100%") || !strings.Contains(out.String(), "✓ Pulled llama3") {
+		t.Errorf("Unexpected output: %q", out.String())
+	}
+
+	// OpenAI-compatible servers have no pull API
+	other := New(openai.NewClient(server.URL), WithModel("m"))
+	if err := other.PullModel(context.Background(), "llama3", nil); err == nil {
+		t.Error("Expected an error from a provider that cannot pull")
+	}
+}
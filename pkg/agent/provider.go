@@ -0,0 +1,32 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// Provider is a model backend: *ollama.Client, or openai.Client for servers
+// with an OpenAI-compatible API. Requests, replies and stream events use the
+// ollama package's types whichever backend serves them. Every call takes a
+// context, so callers can cancel it or give it a deadline.
+type Provider interface {
+	// CreateChatCompletionWithContext returns the complete reply to a chat request
+	CreateChatCompletionWithContext(ctx context.Context, req *ollama.ChatRequest) (*ollama.ChatResponse, error)
+	// ChatEvents streams the reply to a chat request as typed events
+	ChatEvents(ctx context.Context, req *ollama.ChatRequest) (<-chan ollama.StreamEvent, error)
+	// CreateEmbeddingsWithContext returns the embedding of a prompt
+	CreateEmbeddingsWithContext(ctx context.Context, req *ollama.EmbeddingsRequest) (*ollama.EmbeddingsResponse, error)
+	// ListModelsWithContext lists the models the backend offers
+	ListModelsWithContext(ctx context.Context) (*ollama.ListModelsResponse, error)
+	// ShowModelWithContext describes a model: its parameters and capabilities
+	ShowModelWithContext(ctx context.Context, name string) (*ollama.ShowModelResponse, error)
+}
+
+// generator is implemented by providers with a raw completion API, which the
+// generate API mode uses. Other providers are always talked to with chat.
+type generator interface {
+	GenerateEvents(ctx context.Context, req *ollama.GenerateRequest) (<-chan ollama.StreamEvent, error)
+}
+
+var _ Provider = (*ollama.Client)(nil)
@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aykay76/llmapi/pkg/openai"
+)
+
+var _ Provider = (*openai.Client)(nil)
+
+func TestAgent_OpenAIProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			fmt.Fprint(w, `{"data":[{"id":"local","meta":{"n_ctx_train":4096}}]}`)
+		case "/v1/chat/completions":
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hi there\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":9,\"completion_tokens\":2}}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+		default:
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	agent := New(openai.NewClient(server.URL+"/v1"), WithModel("local"))
+	if agent.contextWindow() != 4096 {
+		t.Errorf("Expected the context window from the model list, got %d", agent.contextWindow())
+	}
+
+	// The generate API is an Ollama feature; other providers stay on chat
+	agent.SetAPIMode("local", APIModeGenerate)
+	if agent.apiMode() != APIModeChat {
+		t.Errorf("Expected the chat API, got %q", agent.apiMode())
+	}
+
+	var reply string
+	err := agent.SendMessage(context.Background(), "Hello", func(chunk string) error {
+		reply += chunk
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if reply != "Hi there" {
+		t.Errorf("Expected %q, got %q", "Hi there", reply)
+	}
+	if stats := agent.turnStats[0]; stats.PromptTokens != 9 || stats.ResponseTokens != 2 {
+		t.Errorf("Unexpected turn stats: %+v", stats)
+	}
+}
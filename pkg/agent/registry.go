@@ -0,0 +1,352 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// actionNameRegex restricts action names to what works as both an XML tag and
+// a tool name
+var actionNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ActionDefinition describes a type of action: the tag and tool name models
+// use for it, how to parse it and how to run it.
+//
+// Actions with their own type provide ParseJSON (and optionally ParseXML) and
+// implement Validate and Execute as methods. Simpler actions only provide
+// Validate and Execute here and work on the arguments directly, taken from
+// the child tags of the XML form or the fields of the JSON form.
+type ActionDefinition struct {
+	// Name is the XML tag and tool name, e.g. "run_tests"
+	Name string
+	// Description, Parameters and Required describe the action to models as
+	// a native tool and in the system prompt
+	Description string
+	Parameters  map[string]ollama.ToolProperty
+	Required    []string
+
+	// ParseXML builds an action from the text between <name> and </name>,
+	// returning nil if the text is not a valid use of the tag. By default the
+	// child tags are passed to ParseJSON as arguments.
+	ParseXML func(body string) Action
+	// ParseJSON builds an action from tool call arguments, a JSON block or a
+	// saved session. By default the arguments are kept as strings for
+	// Validate and Execute.
+	ParseJSON func(args map[string]interface{}) Action
+
+	// Validate checks the arguments of an action built by the default
+	// ParseJSON; required parameters are checked before it is called
+	Validate func(args map[string]string) error
+	// Execute runs an action built by the default ParseJSON. The output is
+	// fed back to the model.
+	Execute func(ctx context.Context, workDir string, args map[string]string) (string, error)
+
+	tagRegex *regexp.Regexp
+	builtin  bool
+}
+
+// ActionRegistry holds the action types the agent understands
+type ActionRegistry struct {
+	defs   []*ActionDefinition
+	byName map[string]*ActionDefinition
+}
+
+// NewActionRegistry creates an empty registry
+func NewActionRegistry() *ActionRegistry {
+	return &ActionRegistry{byName: make(map[string]*ActionDefinition)}
+}
+
+// DefaultActionRegistry creates a registry holding the built-in actions
+func DefaultActionRegistry() *ActionRegistry {
+	r := NewActionRegistry()
+	for _, def := range builtinActions() {
+		def.builtin = true
+		if err := r.Register(def); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds an action type. Names must be unique lowercase identifiers.
+func (r *ActionRegistry) Register(def ActionDefinition) error {
+	if !actionNameRegex.MatchString(def.Name) {
+		return fmt.Errorf("invalid action name %q (use lowercase letters, digits and '_')", def.Name)
+	}
+	if _, exists := r.byName[def.Name]; exists {
+		return fmt.Errorf("action %q is already registered", def.Name)
+	}
+	if def.ParseJSON == nil && def.Execute == nil {
+		return fmt.Errorf("action %q needs a ParseJSON parser or an Execute function", def.Name)
+	}
+
+	d := def
+	if d.ParseJSON == nil {
+		d.ParseJSON = func(args map[string]interface{}) Action {
+			return &customAction{def: &d, args: stringArgs(args)}
+		}
+	}
+	if d.ParseXML == nil {
+		d.ParseXML = func(body string) Action {
+			tags := childTags(body)
+			if len(tags) == 0 && len(d.Required) > 0 {
+				// Most likely the tag was only mentioned
+				return nil
+			}
+			args := make(map[string]interface{}, len(tags))
+			for name, value := range tags {
+				args[name] = value
+			}
+			return d.ParseJSON(args)
+		}
+	}
+	d.tagRegex = regexp.MustCompile(`(?s)<` + d.Name + `>(.*?)</` + d.Name + `>`)
+
+	r.defs = append(r.defs, &d)
+	r.byName[d.Name] = &d
+	return nil
+}
+
+// Lookup returns the definition of the named action
+func (r *ActionRegistry) Lookup(name string) (ActionDefinition, bool) {
+	def, ok := r.byName[name]
+	if !ok {
+		return ActionDefinition{}, false
+	}
+	return *def, true
+}
+
+// Names returns the registered action names in registration order
+func (r *ActionRegistry) Names() []string {
+	names := make([]string, len(r.defs))
+	for i, def := range r.defs {
+		names[i] = def.Name
+	}
+	return names
+}
+
+// Tools describes every registered action as a native tool so models that
+// support function calling can request actions without XML tags
+func (r *ActionRegistry) Tools() []ollama.Tool {
+	tools := make([]ollama.Tool, len(r.defs))
+	for i, def := range r.defs {
+		tools[i] = ollama.Tool{
+			Type: "function",
+			Function: ollama.ToolFunction{
+				Name:        def.Name,
+				Description: def.Description,
+				Parameters: ollama.ToolParameters{
+					Type:       "object",
+					Required:   def.Required,
+					Properties: def.Parameters,
+				},
+			},
+		}
+	}
+	return tools
+}
+
+// FromToolCall converts a tool call requested by the model into an Action.
+// Unknown tools yield an action that fails validation.
+func (r *ActionRegistry) FromToolCall(call ollama.ToolCall) Action {
+	def, ok := r.byName[call.Function.Name]
+	if !ok {
+		return &unsupportedToolAction{name: call.Function.Name}
+	}
+	args := call.Function.Arguments
+	if args == nil {
+		args = make(map[string]interface{})
+	}
+	return def.ParseJSON(args)
+}
+
+// parseTags extracts every use of a registered tag, grouped by action type
+func (r *ActionRegistry) parseTags(response string) []Action {
+	var actions []Action
+	for _, def := range r.defs {
+		for _, match := range def.tagRegex.FindAllStringSubmatch(response, -1) {
+			if action := def.ParseXML(match[1]); action != nil {
+				actions = append(actions, action)
+			}
+		}
+	}
+	return actions
+}
+
+// promptSection describes the registered actions that are not built in, for
+// the system prompt
+func (r *ActionRegistry) promptSection() string {
+	var b strings.Builder
+	for _, def := range r.defs {
+		if def.builtin {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("Additional actions are available:\n")
+		}
+		fmt.Fprintf(&b, "\n<%s>\n", def.Name)
+		params := make([]string, 0, len(def.Parameters))
+		for name := range def.Parameters {
+			params = append(params, name)
+		}
+		sort.Strings(params)
+		for _, name := range params {
+			fmt.Fprintf(&b, "<%s>%s</%s>\n", name, def.Parameters[name].Description, name)
+		}
+		fmt.Fprintf(&b, "</%s>\n", def.Name)
+		if def.Description != "" {
+			fmt.Fprintf(&b, "%s\n", def.Description)
+		}
+	}
+	return b.String()
+}
+
+// NamedAction is implemented by actions that know the name they are
+// registered under. It is used for tool results and saved sessions.
+type NamedAction interface {
+	ActionName() string
+}
+
+// toolName returns the tool name that corresponds to an action
+func toolName(action Action) string {
+	if named, ok := action.(NamedAction); ok {
+		return named.ActionName()
+	}
+	return ""
+}
+
+// customAction is an action built from its arguments by the default parsers
+// and run by the Validate and Execute functions of its definition
+type customAction struct {
+	def  *ActionDefinition
+	args map[string]string
+
+	// Populated by Execute
+	output string
+}
+
+func (a *customAction) Execute(ctx context.Context, workDir string) error {
+	if a.def.Execute == nil {
+		return fmt.Errorf("action %s has no executor", a.def.Name)
+	}
+	output, err := a.def.Execute(ctx, workDir, a.args)
+	a.output = output
+	return err
+}
+
+// Output returns the output of the last run
+func (a *customAction) Output() string {
+	return a.output
+}
+
+func (a *customAction) Validate() error {
+	for _, name := range a.def.Required {
+		if strings.TrimSpace(a.args[name]) == "" {
+			return fmt.Errorf("%s is required", name)
+		}
+	}
+	if a.def.Validate != nil {
+		return a.def.Validate(a.args)
+	}
+	return nil
+}
+
+func (a *customAction) String() string {
+	names := make([]string, 0, len(a.args))
+	for name := range a.args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%q", name, a.args[name])
+	}
+	return fmt.Sprintf("%s: %s", strings.ToUpper(a.def.Name), strings.Join(parts, ", "))
+}
+
+func (a *customAction) ActionName() string {
+	return a.def.Name
+}
+
+// MarshalJSON stores the arguments, which is the form ParseJSON reads back
+func (a *customAction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.args)
+}
+
+// childTagRegex matches the opening tag of a child element
+var childTagRegex = regexp.MustCompile(`<([A-Za-z_][A-Za-z0-9_-]*)>`)
+
+// childTags returns the trimmed text of each child element in body. Text
+// inside a child element is not searched for further children.
+func childTags(body string) map[string]string {
+	tags := make(map[string]string)
+	for offset := 0; offset < len(body); {
+		loc := childTagRegex.FindStringSubmatchIndex(body[offset:])
+		if loc == nil {
+			break
+		}
+		name := body[offset+loc[2] : offset+loc[3]]
+		start := offset + loc[1]
+		end := strings.Index(body[start:], "</"+name+">")
+		if end < 0 {
+			offset = start
+			continue
+		}
+		tags[name] = strings.TrimSpace(body[start : start+end])
+		offset = start + end + len(name) + 3
+	}
+	return tags
+}
+
+// stringArgs converts JSON arguments to strings. JSON numbers decode as
+// float64 and are written without a fraction where possible.
+func stringArgs(args map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(args))
+	for name, value := range args {
+		switch v := value.(type) {
+		case string:
+			out[name] = v
+		case float64:
+			out[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			out[name] = strconv.FormatBool(v)
+		case nil:
+		default:
+			data, err := json.Marshal(v)
+			if err == nil {
+				out[name] = string(data)
+			}
+		}
+	}
+	return out
+}
+
+// unsupportedToolAction stands in for a tool call that does not map to any
+// action, so the failure is reported back to the model like any other result.
+type unsupportedToolAction struct {
+	name string
+}
+
+func (a *unsupportedToolAction) Execute(ctx context.Context, workDir string) error {
+	return a.Validate()
+}
+
+func (a *unsupportedToolAction) Validate() error {
+	return fmt.Errorf("unknown tool: %s", a.name)
+}
+
+func (a *unsupportedToolAction) String() string {
+	return fmt.Sprintf("UNKNOWN_TOOL: %s", a.name)
+}
+
+func (a *unsupportedToolAction) ActionName() string {
+	return a.name
+}
@@ -0,0 +1,189 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+func TestActionTools_CoverToolNames(t *testing.T) {
+	registry := DefaultActionRegistry()
+	for _, tool := range registry.Tools() {
+		call := ollama.ToolCall{Function: ollama.ToolCallFunction{Name: tool.Function.Name}}
+		action := registry.FromToolCall(call)
+		if _, ok := action.(*unsupportedToolAction); ok {
+			t.Errorf("Tool %q does not map to an action", tool.Function.Name)
+			continue
+		}
+		if got := toolName(action); got != tool.Function.Name {
+			t.Errorf("Expected tool name %q, got %q", tool.Function.Name, got)
+		}
+	}
+}
+
+func TestActionFromToolCall(t *testing.T) {
+	call := ollama.ToolCall{Function: ollama.ToolCallFunction{
+		Name: "modify_file",
+		Arguments: map[string]interface{}{
+			"path":    " main.go ",
+			"search":  "World",
+			"replace": "Go",
+		},
+	}}
+
+	action := DefaultActionRegistry().FromToolCall(call)
+	modify, ok := action.(*ModifyFileAction)
+	if !ok {
+		t.Fatalf("Expected ModifyFileAction, got %T", action)
+	}
+	if modify.Path != "main.go" || modify.Search != "World" || modify.Replace != "Go" {
+		t.Errorf("Unexpected action: %+v", modify)
+	}
+}
+
+func TestActionFromToolCall_Unknown(t *testing.T) {
+	call := ollama.ToolCall{Function: ollama.ToolCallFunction{Name: "launch_rocket"}}
+
+	action := DefaultActionRegistry().FromToolCall(call)
+	if err := action.Validate(); err == nil {
+		t.Error("Expected unknown tool to fail validation")
+	}
+	if toolName(action) != "launch_rocket" {
+		t.Errorf("Expected tool name 'launch_rocket', got %q", toolName(action))
+	}
+}
+
+func TestActionRegistry_Register(t *testing.T) {
+	registry := DefaultActionRegistry()
+
+	for _, def := range []ActionDefinition{
+		{Name: "Run-Tests", Execute: func(context.Context, string, map[string]string) (string, error) { return "", nil }},
+		{Name: "read_file", Execute: func(context.Context, string, map[string]string) (string, error) { return "", nil }},
+		{Name: "no_executor"},
+	} {
+		if err := registry.Register(def); err == nil {
+			t.Errorf("Expected registering %q to fail", def.Name)
+		}
+	}
+}
+
+func TestActionRegistry_CustomAction(t *testing.T) {
+	var ran map[string]string
+	registry := DefaultActionRegistry()
+	err := registry.Register(ActionDefinition{
+		Name:        "run_tests",
+		Description: "Run the test suite",
+		Required:    []string{"package"},
+		Parameters: map[string]ollama.ToolProperty{
+			"package": {Type: "string", Description: "Package pattern"},
+			"verbose": {Type: "boolean", Description: "Show every test"},
+		},
+		Validate: func(args map[string]string) error {
+			if strings.Contains(args["package"], " ") {
+				return errors.New("package must not contain spaces")
+			}
+			return nil
+		},
+		Execute: func(ctx context.Context, workDir string, args map[string]string) (string, error) {
+			ran = args
+			return "ok  \t" + args["package"], nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	response := "Running the tests:\n<run_tests>\n<package>./pkg/...</package>\n<verbose>true</verbose>\n</run_tests>\n" +
+		"<read_file>\n<path>go.mod</path>\n</read_file>"
+	actions := NewRegistryParser(registry).Parse(response)
+	if len(actions) != 2 {
+		t.Fatalf("Expected 2 actions, got %d", len(actions))
+	}
+
+	// Registration order: built-ins first
+	if _, ok := actions[0].(*ReadFileAction); !ok {
+		t.Errorf("Expected READ_FILE first, got %s", actions[0].String())
+	}
+	custom := actions[1]
+	if got := custom.String(); got != `RUN_TESTS: package="./pkg/...", verbose="true"` {
+		t.Errorf("Unexpected String(): %s", got)
+	}
+	if toolName(custom) != "run_tests" {
+		t.Errorf("Expected tool name run_tests, got %q", toolName(custom))
+	}
+
+	results, err := ExecuteActions(context.Background(), []Action{custom}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if ran["package"] != "./pkg/..." || results[0].Output != "ok  \t./pkg/..." {
+		t.Errorf("Unexpected run: args=%v output=%q", ran, results[0].Output)
+	}
+
+	// The JSON form goes through the same validation
+	call := ollama.ToolCall{Function: ollama.ToolCallFunction{
+		Name:      "run_tests",
+		Arguments: map[string]interface{}{"package": "a b"},
+	}}
+	if err := registry.FromToolCall(call).Validate(); err == nil {
+		t.Error("Expected the custom validator to reject the arguments")
+	}
+	call.Function.Arguments = map[string]interface{}{"verbose": true}
+	if err := registry.FromToolCall(call).Validate(); err == nil || !strings.Contains(err.Error(), "package is required") {
+		t.Errorf("Expected a missing required argument to fail, got %v", err)
+	}
+
+	// Custom actions survive a session round trip and are described to the model
+	calls, err := encodeActions([]Action{custom})
+	if err != nil {
+		t.Fatalf("encodeActions failed: %v", err)
+	}
+	decoded := decodeActions(registry, calls)
+	if decoded[0].String() != custom.String() {
+		t.Errorf("Expected %s after decoding, got %s", custom.String(), decoded[0].String())
+	}
+	if section := registry.promptSection(); !strings.Contains(section, "<run_tests>\n<package>Package pattern</package>") {
+		t.Errorf("Unexpected prompt section:\n%s", section)
+	}
+}
+
+func TestAgent_GenerateDescribesCustomActions(t *testing.T) {
+	var system string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/generate" {
+			http.NotFound(w, r)
+			return
+		}
+		var req ollama.GenerateRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		system = req.System
+		fmt.Fprintln(w, `{"response":"Ok.","done":false}`)
+		fmt.Fprintln(w, `{"response":"","done":true}`)
+	}))
+	defer server.Close()
+
+	agent := New(ollama.NewClient(server.URL), WithModel("m"), WithSystemPrompt("Be brief."))
+	agent.SetAPIMode("m", APIModeGenerate)
+	err := agent.RegisterAction(ActionDefinition{
+		Name:        "run_tests",
+		Description: "Run the test suite",
+		Execute:     func(context.Context, string, map[string]string) (string, error) { return "", nil },
+	})
+	if err != nil {
+		t.Fatalf("RegisterAction failed: %v", err)
+	}
+
+	if err := agent.SendMessage(context.Background(), "Test it", nil); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	if !strings.HasPrefix(system, "Be brief.") || !strings.Contains(system, "<run_tests>") {
+		t.Errorf("Expected the system prompt to describe the custom action, got:\n%s", system)
+	}
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ActionStatus is the outcome of an action in a batch
+type ActionStatus string
+
+const (
+	// StatusSucceeded means the action ran without error
+	StatusSucceeded ActionStatus = "succeeded"
+	// StatusFailed means the action was invalid, refused or failed to run
+	StatusFailed ActionStatus = "failed"
+	// StatusSkipped means the user skipped the action during review
+	StatusSkipped ActionStatus = "skipped"
+	// StatusNotRun means the action was never attempted, because the batch
+	// was aborted or could not start
+	StatusNotRun ActionStatus = "not_run"
+	// StatusRolledBack means the action succeeded but its changes were undone
+	// because another action in the batch failed
+	StatusRolledBack ActionStatus = "rolled_back"
+)
+
+// ActionResult describes what happened to one action in a batch
+type ActionResult struct {
+	Action       Action
+	Status       ActionStatus
+	Err          error         // nil only for StatusSucceeded
+	Output       string        // the action's output, if it produces any
+	Duration     time.Duration // time spent executing the action
+	BytesWritten int           // bytes written to files
+}
+
+// writingAction is implemented by actions that write files, so the executor
+// can report how much they wrote
+type writingAction interface {
+	bytesWritten() int
+}
+
+// MarshalJSON renders the result with the action described by its String and
+// tool name, the error as text and the duration in milliseconds
+func (r ActionResult) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Action       string       `json:"action"`
+		Type         string       `json:"type,omitempty"`
+		Status       ActionStatus `json:"status"`
+		Error        string       `json:"error,omitempty"`
+		Output       string       `json:"output,omitempty"`
+		DurationMS   int64        `json:"duration_ms"`
+		BytesWritten int          `json:"bytes_written,omitempty"`
+	}{
+		Status:       r.Status,
+		Output:       r.Output,
+		DurationMS:   r.Duration.Milliseconds(),
+		BytesWritten: r.BytesWritten,
+	}
+	if r.Action != nil {
+		out.Action = r.Action.String()
+		out.Type = toolName(r.Action)
+	}
+	if r.Err != nil {
+		out.Error = r.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// BatchError is returned when some actions in a batch did not succeed. It
+// unwraps to the error of each of them, so errors.Is and errors.As can look
+// through the whole batch.
+type BatchError struct {
+	Results []ActionResult
+}
+
+// newBatchError returns a BatchError for results, or nil if every action
+// succeeded
+func newBatchError(results []ActionResult) error {
+	for _, r := range results {
+		if r.Err != nil {
+			return &BatchError{Results: results}
+		}
+	}
+	return nil
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("completed with %d failure(s)", len(e.Unwrap()))
+}
+
+// Unwrap returns the error of every action that did not succeed
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, r := range e.Results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return errs
+}
+
+// FormatResults renders the results of a batch as a message that can be fed
+// back to the model
+func FormatResults(results []ActionResult) string {
+	var b strings.Builder
+	b.WriteString("Action results:\n")
+
+	for i, r := range results {
+		fmt.Fprintf(&b, "\n[%d/%d] %s\n", i+1, len(results), r.Action.String())
+		fmt.Fprintf(&b, "status: %s\n", r.Status)
+		if r.Err != nil {
+			fmt.Fprintf(&b, "error: %v\n", r.Err)
+		}
+		if r.Output != "" {
+			fmt.Fprintf(&b, "output:\n%s\n", r.Output)
+		}
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FormatActionResults renders the outcome of executed actions as a message
+// that can be fed back to the model. errs must hold one entry per action.
+func FormatActionResults(actions []Action, errs []error) string {
+	results := make([]ActionResult, len(actions))
+	for i, action := range actions {
+		results[i] = ActionResult{Action: action, Status: StatusSucceeded}
+		if i < len(errs) && errs[i] != nil {
+			results[i].Status = StatusFailed
+			results[i].Err = errs[i]
+		}
+		if out, ok := action.(ActionOutput); ok {
+			results[i].Output = out.Output()
+		}
+	}
+	return FormatResults(results)
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestExecutor_Results(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	actions := []Action{
+		&CreateFileAction{Path: "notes.txt", Content: "remember the milk"},
+		&ModifyFileAction{Path: "notes.txt", Search: "milk", Replace: "bread"},
+		&ReadFileAction{Path: "missing.txt"},
+	}
+	results, err := NewExecutor(tmpDir).Execute(context.Background(), actions)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected a *BatchError, got %v", err)
+	}
+	if err.Error() != "completed with 1 failure(s)" {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Error("Expected the batch error to unwrap to the read failure")
+	}
+
+	if results[0].Status != StatusSucceeded || results[0].BytesWritten != 17 {
+		t.Errorf("Unexpected create result: %+v", results[0])
+	}
+	if results[1].Status != StatusSucceeded || results[1].BytesWritten != 18 {
+		t.Errorf("Unexpected modify result: %+v", results[1])
+	}
+	if results[2].Status != StatusFailed || results[2].Err == nil {
+		t.Errorf("Unexpected read result: %+v", results[2])
+	}
+}
+
+func TestActionResult_MarshalJSON(t *testing.T) {
+	results := []ActionResult{
+		{Action: &CreateFileAction{Path: "a.txt", Content: "hi"}, Status: StatusSucceeded, BytesWritten: 2},
+		{Action: &ReadFileAction{Path: "b.txt"}, Status: StatusSkipped, Err: errors.New("action 2 skipped by user")},
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		`"action":"CREATE_FILE: a.txt (2 bytes)","type":"create_file","status":"succeeded"`,
+		`"bytes_written":2`,
+		`"status":"skipped","error":"action 2 skipped by user"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected JSON to contain %s, got %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ReviewDecision is the user's choice when reviewing an action
+type ReviewDecision int
+
+const (
+	// ReviewApprove runs the action
+	ReviewApprove ReviewDecision = iota
+	// ReviewSkip leaves the action out and moves on to the next one
+	ReviewSkip
+	// ReviewEdit replaces the action with the edited one returned alongside,
+	// which is reviewed again
+	ReviewEdit
+	// ReviewAbort stops the batch; this and the remaining actions don't run
+	ReviewAbort
+)
+
+// ApproveFunc reviews an action before it runs. preview shows what the action
+// will do. For ReviewEdit the edited action is returned as well.
+type ApproveFunc func(action Action, preview string) (ReviewDecision, Action)
+
+// previewAction describes what an action is about to do: a unified diff for
+// file changes, the full command for commands
+func previewAction(ctx context.Context, workDir string, action Action) string {
+	switch a := action.(type) {
+	case *CreateFileAction:
+		fullPath, err := resolvePath(ctx, workDir, a.Path, true)
+		if err != nil {
+			return fmt.Sprintf("cannot preview: %v", err)
+		}
+		existing, err := os.ReadFile(fullPath)
+		if os.IsNotExist(err) {
+			return unifiedDiff("", a.Path, "", a.Content)
+		}
+		if err != nil {
+			return fmt.Sprintf("cannot preview: %v", err)
+		}
+		if string(existing) == a.Content {
+			return "(no changes: the file already has this content)"
+		}
+		return "⚠️  Overwrites an existing file\n" + unifiedDiff(a.Path, a.Path, string(existing), a.Content)
+
+	case *ModifyFileAction:
+		fullPath, err := resolvePath(ctx, workDir, a.Path, true)
+		if err != nil {
+			return fmt.Sprintf("cannot preview: %v", err)
+		}
+		existing, err := os.ReadFile(fullPath)
+		if err != nil {
+			return fmt.Sprintf("cannot preview: %v", err)
+		}
+		modified, err := a.apply(string(existing))
+		if err != nil {
+			return fmt.Sprintf("cannot preview: %v", err)
+		}
+		return unifiedDiff(a.Path, a.Path, string(existing), modified)
+
+	case *ApplyPatchAction:
+		return strings.TrimRight(a.Patch, "\n")
+
+	case *ExecuteCommandAction:
+		mode := "direct"
+		if commandOptions(ctx).Shell {
+			mode = "/bin/sh -c"
+		}
+		return fmt.Sprintf("$ %s\n  (in %s, %s)", a.Command, workDir, mode)
+	}
+
+	return action.String()
+}
+
+// reviewAction shows an action and asks the user on the terminal what to do
+// with it
+func (a *Agent) reviewAction(action Action, preview string) (ReviewDecision, Action) {
+	fmt.Fprintln(a.out, preview)
+
+	for {
+		fmt.Fprint(a.out, "[a]pprove, [s]kip, [e]dit or a[b]ort? ")
+		answer, err := a.stdin().ReadString('\n')
+		if err != nil {
+			return ReviewAbort, nil
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "a", "approve", "y", "yes":
+			return ReviewApprove, nil
+		case "s", "skip", "n", "no":
+			return ReviewSkip, nil
+		case "b", "abort":
+			return ReviewAbort, nil
+		case "e", "edit":
+			edited, err := a.editAction(action)
+			if err != nil {
+				fmt.Fprintf(a.out, "✖ %v\n", err)
+				continue
+			}
+			return ReviewEdit, edited
+		}
+	}
+}
+
+// editAction lets the user change a command on the terminal, or the content
+// of a file action in their editor
+func (a *Agent) editAction(action Action) (Action, error) {
+	switch act := action.(type) {
+	case *ExecuteCommandAction:
+		fmt.Fprintf(a.out, "Command (empty to keep):\n  %s\n> ", act.Command)
+		line, err := a.stdin().ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read command: %w", err)
+		}
+		command := strings.TrimSpace(line)
+		if command == "" {
+			command = act.Command
+		}
+		return &ExecuteCommandAction{Command: command, Description: act.Description}, nil
+
+	case *CreateFileAction:
+		content, err := editInEditor(act.Content, filepath.Ext(act.Path))
+		if err != nil {
+			return nil, err
+		}
+		return &CreateFileAction{Path: act.Path, Content: content}, nil
+
+	case *ModifyFileAction:
+		replace, err := editInEditor(act.Replace, filepath.Ext(act.Path))
+		if err != nil {
+			return nil, err
+		}
+		edited := *act
+		edited.Replace = replace
+		return &edited, nil
+
+	case *ApplyPatchAction:
+		patch, err := editInEditor(act.Patch, ".diff")
+		if err != nil {
+			return nil, err
+		}
+		return &ApplyPatchAction{Path: act.Path, Patch: patch}, nil
+	}
+
+	return nil, fmt.Errorf("%s can't be edited", action.String())
+}
+
+// editInEditor opens text in $VISUAL or $EDITOR (vi by default) and returns
+// the saved result
+func editInEditor(text, ext string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	tmp, err := os.CreateTemp("", "llmapi-edit-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(text); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	tmp.Close()
+
+	// The editor setting may include arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], tmp.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited file: %w", err)
+	}
+	return string(edited), nil
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreviewAction(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte("port: 8080\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	ctx := context.Background()
+
+	preview := previewAction(ctx, tmpDir, &ModifyFileAction{Path: "config.yaml", Search: "8080", Replace: "9090"})
+	if !strings.Contains(preview, "-port: 8080\n+port: 9090") {
+		t.Errorf("Expected a diff for the modification, got:\n%s", preview)
+	}
+
+	preview = previewAction(ctx, tmpDir, &CreateFileAction{Path: "config.yaml", Content: "port: 80\n"})
+	if !strings.Contains(preview, "Overwrites an existing file") || !strings.Contains(preview, "+port: 80") {
+		t.Errorf("Expected an overwrite diff, got:\n%s", preview)
+	}
+
+	preview = previewAction(ctx, tmpDir, &CreateFileAction{Path: "new.txt", Content: "hello\n"})
+	if !strings.Contains(preview, "--- /dev/null") {
+		t.Errorf("Expected a new-file diff, got:\n%s", preview)
+	}
+
+	preview = previewAction(ctx, tmpDir, &ExecuteCommandAction{Command: "go test ./..."})
+	if !strings.Contains(preview, "$ go test ./...") {
+		t.Errorf("Expected the full command, got:\n%s", preview)
+	}
+}
+
+func TestExecutor_Approver(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	actions := []Action{
+		&CreateFileAction{Path: "approved.txt", Content: "yes"},
+		&CreateFileAction{Path: "skipped.txt", Content: "no"},
+		&CreateFileAction{Path: "edited.txt", Content: "draft"},
+		&CreateFileAction{Path: "aborted.txt", Content: "no"},
+		&CreateFileAction{Path: "never.txt", Content: "no"},
+	}
+
+	executor := NewExecutor(tmpDir)
+	executor.SetApprover(func(action Action, preview string) (ReviewDecision, Action) {
+		create := action.(*CreateFileAction)
+		switch create.Path {
+		case "skipped.txt":
+			return ReviewSkip, nil
+		case "edited.txt":
+			if create.Content == "draft" {
+				return ReviewEdit, &CreateFileAction{Path: create.Path, Content: "final"}
+			}
+		case "aborted.txt":
+			return ReviewAbort, nil
+		}
+		return ReviewApprove, nil
+	})
+
+	results := executor.run(context.Background(), actions)
+
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Errorf("Expected approved actions to succeed, got %v and %v", results[0].Err, results[2].Err)
+	}
+	for i, want := range map[int]ActionStatus{1: StatusSkipped, 3: StatusNotRun, 4: StatusNotRun} {
+		if results[i].Err == nil || results[i].Status != want {
+			t.Errorf("Expected action %d to be %s, got %s (err=%v)", i+1, want, results[i].Status, results[i].Err)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "edited.txt"))
+	if err != nil || string(content) != "final" {
+		t.Errorf("Expected edited content to be written, got %q (err=%v)", string(content), err)
+	}
+	if actions[2].(*CreateFileAction).Content != "final" {
+		t.Error("Expected the edited action to replace the original")
+	}
+	for _, name := range []string{"skipped.txt", "aborted.txt", "never.txt"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s not to be created, got err=%v", name, err)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sandbox confines the paths used by actions to a working directory. Paths
+// are canonicalized against the working directory and symlinks are resolved
+// before checking, so neither "..", absolute paths nor links can escape it.
+// Extra roots may be allowed for reading only.
+type Sandbox struct {
+	root      string
+	readRoots []string
+}
+
+// NewSandbox creates a sandbox rooted at workDir. Paths under readRoots may be
+// read but never written.
+func NewSandbox(workDir string, readRoots ...string) (*Sandbox, error) {
+	root, err := canonicalDir(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid working directory: %w", err)
+	}
+
+	sb := &Sandbox{root: root}
+	for _, r := range readRoots {
+		canonical, err := canonicalDir(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid read root %s: %w", r, err)
+		}
+		sb.readRoots = append(sb.readRoots, canonical)
+	}
+	return sb, nil
+}
+
+// Root returns the canonical working directory
+func (s *Sandbox) Root() string {
+	return s.root
+}
+
+// Resolve returns the canonical absolute path for path, which may be relative
+// to the working directory or absolute. Writable paths must lie inside the
+// working directory; readable paths may also lie inside a read root.
+func (s *Sandbox) Resolve(path string, write bool) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("path cannot be empty")
+	}
+
+	candidate := path
+	if !filepath.IsAbs(candidate) {
+		candidate = filepath.Join(s.root, candidate)
+	}
+
+	resolved, err := resolveSymlinks(filepath.Clean(candidate))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+
+	if within(s.root, resolved) {
+		return resolved, nil
+	}
+	if !write {
+		for _, r := range s.readRoots {
+			if within(r, resolved) {
+				return resolved, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("path %s is outside the working directory", path)
+}
+
+// canonicalDir returns the absolute, symlink-free form of an existing directory
+func canonicalDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory", dir)
+	}
+	return resolved, nil
+}
+
+// maxSymlinks bounds the links followed while resolving a path, like the
+// kernel's limit, so that link cycles fail instead of looping
+const maxSymlinks = 40
+
+// resolveSymlinks resolves symlinks in the longest existing prefix of an
+// absolute path and appends the components that do not exist yet. A
+// dangling symlink is followed to its target, so that writing through it
+// is checked against the target's location rather than the link's.
+func resolveSymlinks(path string) (string, error) {
+	return resolveSymlinksDepth(path, 0)
+}
+
+// resolveSymlinksDepth is resolveSymlinks having followed depth dangling links
+func resolveSymlinksDepth(path string, depth int) (string, error) {
+	var missing []string
+	current := path
+	for {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return joinMissing(resolved, missing), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		// EvalSymlinks reports a dangling link as missing; follow it by hand
+		if info, lerr := os.Lstat(current); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+			if depth >= maxSymlinks {
+				return "", fmt.Errorf("too many levels of symbolic links")
+			}
+			target, err := os.Readlink(current)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(current), target)
+			}
+			resolved, err := resolveSymlinksDepth(filepath.Clean(target), depth+1)
+			if err != nil {
+				return "", err
+			}
+			return joinMissing(resolved, missing), nil
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path, nil
+		}
+		missing = append(missing, filepath.Base(current))
+		current = parent
+	}
+}
+
+// joinMissing appends the missing components, collected innermost first, to
+// a resolved path
+func joinMissing(resolved string, missing []string) string {
+	for i := len(missing) - 1; i >= 0; i-- {
+		resolved = filepath.Join(resolved, missing[i])
+	}
+	return resolved
+}
+
+// within reports whether path is root or lies beneath it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// validateActionPath performs the checks that don't need a working directory:
+// the path must be present and must not contain ".." components
+func validateActionPath(path string) error {
+	if path == "" {
+		return fmt.Errorf("path cannot be empty")
+	}
+	for _, part := range strings.FieldsFunc(filepath.ToSlash(path), func(r rune) bool { return r == '/' }) {
+		if part == ".." {
+			return fmt.Errorf("path cannot contain '..'")
+		}
+	}
+	return nil
+}
+
+type sandboxKey struct{}
+
+// withSandbox returns a context that carries the sandbox used by actions
+func withSandbox(ctx context.Context, sb *Sandbox) context.Context {
+	return context.WithValue(ctx, sandboxKey{}, sb)
+}
+
+// resolvePath resolves an action path with the sandbox carried by ctx, or a
+// sandbox rooted at workDir when there is none
+func resolvePath(ctx context.Context, workDir, path string, write bool) (string, error) {
+	sb, ok := ctx.Value(sandboxKey{}).(*Sandbox)
+	if !ok {
+		var err error
+		if sb, err = NewSandbox(workDir); err != nil {
+			return "", err
+		}
+	}
+	return sb.Resolve(path, write)
+}
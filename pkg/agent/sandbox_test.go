@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSandbox_Resolve(t *testing.T) {
+	workDir := t.TempDir()
+	outside := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(workDir, "link")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	// Dangling links: the targets don't exist yet
+	if err := os.Symlink(filepath.Join(outside, "pwned.txt"), filepath.Join(workDir, "evil")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink("new.txt", filepath.Join(workDir, "pending")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink("loop", filepath.Join(workDir, "loop")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	sb, err := NewSandbox(workDir, outside)
+	if err != nil {
+		t.Fatalf("NewSandbox failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		write   bool
+		wantErr bool
+	}{
+		{"relative path", "src/main.go", true, false},
+		{"absolute path inside", filepath.Join(workDir, "main.go"), true, false},
+		{"absolute path outside", "/etc/passwd", false, true},
+		{"parent directory escape", "../escape.txt", true, true},
+		{"symlink escape on write", "link/secret.txt", true, true},
+		{"dangling symlink escape on write", "evil", true, true},
+		{"dangling symlink escape below", "evil/sub.txt", true, true},
+		{"dangling symlink inside", "pending", true, false},
+		{"symlink cycle", "loop", true, true},
+		{"symlink into read root", "link/secret.txt", false, false},
+		{"read root on write", filepath.Join(outside, "secret.txt"), true, true},
+		{"read root on read", filepath.Join(outside, "secret.txt"), false, false},
+		{"empty path", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := sb.Resolve(tt.path, tt.write)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Resolve(%q, %v) error = %v, wantErr %v", tt.path, tt.write, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestActions_RejectSymlinkEscape(t *testing.T) {
+	workDir := t.TempDir()
+	outside := t.TempDir()
+
+	target := filepath.Join(outside, "config.txt")
+	if err := os.WriteFile(target, []byte("original"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.Symlink(target, filepath.Join(workDir, "config.txt")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	dangling := filepath.Join(outside, "pwned.txt")
+	if err := os.Symlink(dangling, filepath.Join(workDir, "evil")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	ctx := context.Background()
+	actions := []Action{
+		&CreateFileAction{Path: "evil", Content: "pwned"},
+		&CreateFileAction{Path: "config.txt", Content: "overwritten"},
+		&ModifyFileAction{Path: "config.txt", Search: "original", Replace: "modified"},
+		&ReadFileAction{Path: "config.txt"},
+	}
+	for _, action := range actions {
+		if err := action.Execute(ctx, workDir); err == nil {
+			t.Errorf("Expected %s to be rejected", action.String())
+		}
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "original" {
+		t.Errorf("Expected file outside the sandbox to be untouched, got '%s'", string(content))
+	}
+	if _, err := os.Lstat(dangling); !os.IsNotExist(err) {
+		t.Errorf("Expected no file to be created through the dangling link, got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// sessionVersion is the current session file format version
+const sessionVersion = 1
+
+// sessionNameRegex restricts session names to safe file names
+var sessionNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// TurnStats records statistics for one model response
+type TurnStats struct {
+	Model           string        `json:"model"`
+	Time            time.Time     `json:"time"`
+	PromptTokens    int           `json:"prompt_tokens"`
+	ResponseTokens  int           `json:"response_tokens"`
+	TotalDuration   time.Duration `json:"total_duration"`
+	ResponseLength  int           `json:"response_length"`
+	ContextMessages int           `json:"context_messages"`
+}
+
+// Session is the persisted state of a conversation
+type Session struct {
+	Version          int                  `json:"version"`
+	Name             string               `json:"name"`
+	SavedAt          time.Time            `json:"saved_at"`
+	Model            string               `json:"model"`
+	SystemPrompt     string               `json:"system_prompt,omitempty"`
+	WorkDir          string               `json:"work_dir"`
+	History          []ollama.ChatMessage `json:"history"`
+	PendingActions   []ollama.ToolCall    `json:"pending_actions,omitempty"`
+	PendingFromTools bool                 `json:"pending_from_tools,omitempty"`
+	Turns            []TurnStats          `json:"turns,omitempty"`
+}
+
+// SessionInfo summarizes a saved session
+type SessionInfo struct {
+	Name     string
+	SavedAt  time.Time
+	Model    string
+	Messages int
+}
+
+// DefaultSessionDir returns the directory sessions are stored in by default
+func DefaultSessionDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = "."
+	}
+	return filepath.Join(dir, "llmapi", "sessions")
+}
+
+// SetSessionDir sets the directory sessions are saved to and loaded from
+func (a *Agent) SetSessionDir(dir string) {
+	a.sessionDir = dir
+}
+
+// sessionPath returns the file path for a session name
+func (a *Agent) sessionPath(name string) (string, error) {
+	if !sessionNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid session name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return filepath.Join(a.sessionDir, name+".json"), nil
+}
+
+// SaveSession writes the conversation, settings, pending actions and turn
+// statistics to a named session file and returns its path
+func (a *Agent) SaveSession(name string) (string, error) {
+	path, err := a.sessionPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	pending, err := encodeActions(a.pendingActions)
+	if err != nil {
+		return "", err
+	}
+
+	session := Session{
+		Version:          sessionVersion,
+		Name:             name,
+		SavedAt:          time.Now(),
+		Model:            a.modelName,
+		SystemPrompt:     a.systemPrompt,
+		WorkDir:          a.workDir,
+		History:          a.conversationHistory,
+		PendingActions:   pending,
+		PendingFromTools: a.pendingFromTools,
+		Turns:            a.turnStats,
+	}
+
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	if err := os.MkdirAll(a.sessionDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	// Write to a temporary file first so an interrupted save can't corrupt
+	// an existing session
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to write session: %w", err)
+	}
+
+	return path, nil
+}
+
+// LoadSession restores a named session, replacing the current conversation.
+// If the session used another model, that model's details are fetched with
+// ctx; failing to fetch them is reported as a warning, not an error.
+func (a *Agent) LoadSession(ctx context.Context, name string) error {
+	path, err := a.sessionPath(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("session %q not found", name)
+		}
+		return fmt.Errorf("failed to read session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return fmt.Errorf("failed to decode session: %w", err)
+	}
+	if session.Version < 1 || session.Version > sessionVersion {
+		return fmt.Errorf("unsupported session version %d", session.Version)
+	}
+
+	if session.Model != "" && session.Model != a.modelName {
+		a.modelName = session.Model
+		if _, err := a.loadModelInfo(ctx); err != nil {
+			a.emit(Event{Type: EventInfo, Text: fmt.Sprintf("⚠️  Could not fetch details of model %s: %v", a.modelName, err)})
+		}
+	}
+	a.systemPrompt = session.SystemPrompt
+	a.conversationHistory = session.History
+	if a.conversationHistory == nil {
+		a.conversationHistory = make([]ollama.ChatMessage, 0)
+	}
+	a.pendingActions = decodeActions(a.actionRegistry(), session.PendingActions)
+	a.pendingFromTools = session.PendingFromTools
+	a.turnStats = session.Turns
+
+	if session.WorkDir != "" {
+		if info, err := os.Stat(session.WorkDir); err == nil && info.IsDir() {
+			a.workDir = session.WorkDir
+		} else {
+			a.emit(Event{Type: EventInfo, Text: fmt.Sprintf("⚠️  Saved working directory %s no longer exists; keeping %s", session.WorkDir, a.workDir)})
+		}
+	}
+
+	return nil
+}
+
+// ListSessions returns the saved sessions, most recently saved first
+func (a *Agent) ListSessions() ([]SessionInfo, error) {
+	entries, err := os.ReadDir(a.sessionDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read session directory: %w", err)
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(a.sessionDir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		var session Session
+		if err := json.Unmarshal(data, &session); err != nil {
+			continue
+		}
+
+		sessions = append(sessions, SessionInfo{
+			Name:     strings.TrimSuffix(entry.Name(), ".json"),
+			SavedAt:  session.SavedAt,
+			Model:    session.Model,
+			Messages: len(session.History),
+		})
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].SavedAt.After(sessions[j].SavedAt)
+	})
+	return sessions, nil
+}
+
+// encodeActions stores actions in the same form as native tool calls
+func encodeActions(actions []Action) ([]ollama.ToolCall, error) {
+	calls := make([]ollama.ToolCall, 0, len(actions))
+	for _, action := range actions {
+		name := toolName(action)
+		if name == "" {
+			return nil, fmt.Errorf("failed to encode action %s: it does not implement NamedAction", action.String())
+		}
+		data, err := json.Marshal(action)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode action %s: %w", action.String(), err)
+		}
+		var args map[string]interface{}
+		if err := json.Unmarshal(data, &args); err != nil {
+			return nil, fmt.Errorf("failed to encode action %s: %w", action.String(), err)
+		}
+		calls = append(calls, ollama.ToolCall{Function: ollama.ToolCallFunction{
+			Name:      name,
+			Arguments: args,
+		}})
+	}
+	return calls, nil
+}
+
+// decodeActions rebuilds actions stored by encodeActions
+func decodeActions(registry *ActionRegistry, calls []ollama.ToolCall) []Action {
+	if len(calls) == 0 {
+		return nil
+	}
+	actions := make([]Action, 0, len(calls))
+	for _, call := range calls {
+		actions = append(actions, registry.FromToolCall(call))
+	}
+	return actions
+}
@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+func TestSaveLoadSession(t *testing.T) {
+	sessionDir := t.TempDir()
+	workDir := t.TempDir()
+
+	saved := &Agent{
+		modelName:    "test-model",
+		systemPrompt: "You are helpful",
+		workDir:      workDir,
+		sessionDir:   sessionDir,
+		conversationHistory: []ollama.ChatMessage{
+			{Role: "user", Content: "Create main.go"},
+			{Role: "assistant", Content: "<create_file>...</create_file>"},
+		},
+		pendingActions: []Action{
+			&CreateFileAction{Path: "main.go", Content: "package main"},
+			&ExecuteCommandAction{Command: "go build", Description: "Build"},
+		},
+		turnStats: []TurnStats{{Model: "test-model", PromptTokens: 10, ResponseTokens: 20, TotalDuration: time.Second}},
+	}
+
+	path, err := saved.SaveSession("my-session")
+	if err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	if path != filepath.Join(sessionDir, "my-session.json") {
+		t.Errorf("Unexpected session path: %s", path)
+	}
+
+	loaded := &Agent{modelName: "test-model", workDir: ".", sessionDir: sessionDir, out: io.Discard}
+	if err := loaded.LoadSession(context.Background(), "my-session"); err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+
+	if loaded.systemPrompt != "You are helpful" {
+		t.Errorf("Expected system prompt to be restored, got %q", loaded.systemPrompt)
+	}
+	if loaded.workDir != workDir {
+		t.Errorf("Expected work dir %s, got %s", workDir, loaded.workDir)
+	}
+	if len(loaded.conversationHistory) != 2 || loaded.conversationHistory[1].Role != "assistant" {
+		t.Errorf("Unexpected history: %+v", loaded.conversationHistory)
+	}
+	if len(loaded.turnStats) != 1 || loaded.turnStats[0].ResponseTokens != 20 {
+		t.Errorf("Unexpected turn stats: %+v", loaded.turnStats)
+	}
+
+	if len(loaded.pendingActions) != 2 {
+		t.Fatalf("Expected 2 pending actions, got %d", len(loaded.pendingActions))
+	}
+	if create, ok := loaded.pendingActions[0].(*CreateFileAction); !ok || create.Content != "package main" {
+		t.Errorf("Unexpected first pending action: %#v", loaded.pendingActions[0])
+	}
+	if cmd, ok := loaded.pendingActions[1].(*ExecuteCommandAction); !ok || cmd.Command != "go build" {
+		t.Errorf("Unexpected second pending action: %#v", loaded.pendingActions[1])
+	}
+
+	sessions, err := loaded.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Name != "my-session" || sessions[0].Messages != 2 {
+		t.Errorf("Unexpected sessions: %+v", sessions)
+	}
+}
+
+func TestLoadSession_Errors(t *testing.T) {
+	sessionDir := t.TempDir()
+	agent := &Agent{sessionDir: sessionDir}
+
+	if err := agent.LoadSession(context.Background(), "../escape"); err == nil {
+		t.Error("Expected an error for an invalid session name")
+	}
+	if err := agent.LoadSession(context.Background(), "missing"); err == nil {
+		t.Error("Expected an error for a missing session")
+	}
+
+	future := `{"version": 99, "name": "future"}`
+	if err := os.WriteFile(filepath.Join(sessionDir, "future.json"), []byte(future), 0600); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+	if err := agent.LoadSession(context.Background(), "future"); err == nil {
+		t.Error("Expected an error for an unsupported session version")
+	}
+}
+
+func TestLoadSession_ModelInfoUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	sessionDir := t.TempDir()
+	saved := &Agent{modelName: "other-model", workDir: ".", sessionDir: sessionDir}
+	if _, err := saved.SaveSession("other"); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+
+	var events []Event
+	loaded := &Agent{client: ollama.NewClient(server.URL), modelName: "test-model", workDir: ".", sessionDir: sessionDir, out: io.Discard}
+	loaded.SetEventSink(EventSinkFunc(func(ev Event) { events = append(events, ev) }))
+	if err := loaded.LoadSession(context.Background(), "other"); err != nil {
+		t.Fatalf("Expected the session to load without the model details, got %v", err)
+	}
+	if loaded.modelName != "other-model" {
+		t.Errorf("Expected the session's model, got %s", loaded.modelName)
+	}
+	if len(events) != 1 || !strings.Contains(events[0].Text, "other-model") {
+		t.Errorf("Expected a warning about the model details, got %+v", events)
+	}
+}
@@ -0,0 +1,99 @@
+package agent
+
+import "strings"
+
+// StreamParser extracts actions from a response while it is still being
+// generated. Tag actions are returned by Feed as soon as their closing tag
+// arrives, in the order they appear. Fenced JSON and @create-file blocks need
+// the whole response and are returned by Flush.
+type StreamParser struct {
+	parser  *ActionParser
+	buf     strings.Builder
+	offset  int             // start of the text not yet parsed
+	prose   strings.Builder // text before the last parsed tag, without tags
+	tagEnd  int             // end of the last parsed tag
+	actions []Action
+}
+
+// NewStreamParser creates a streaming parser that uses parser for the
+// individual actions
+func NewStreamParser(parser *ActionParser) *StreamParser {
+	return &StreamParser{parser: parser}
+}
+
+// Feed adds the next chunk of the response and returns the actions it
+// completed
+func (s *StreamParser) Feed(chunk string) []Action {
+	s.buf.WriteString(chunk)
+	text := s.buf.String()
+
+	var found []Action
+	for {
+		start, tag := nextActionTag(text, s.offset, s.parser.registry.Names())
+		if start < 0 {
+			// Keep a tag that may be split across chunks for the next call
+			rest := text[s.offset:]
+			if i := strings.LastIndexByte(rest, '<'); i >= 0 && !strings.ContainsRune(rest[i:], '>') {
+				s.offset += i
+			} else {
+				s.offset = len(text)
+			}
+			break
+		}
+
+		closing := "</" + tag + ">"
+		end := strings.Index(text[start:], closing)
+		if end < 0 {
+			// Wait for the rest of the action
+			s.offset = start
+			break
+		}
+		end += start + len(closing)
+
+		found = append(found, s.parser.parseTags(text[start:end])...)
+		s.prose.WriteString(text[s.tagEnd:start])
+		s.tagEnd = end
+		s.offset = end
+	}
+
+	s.actions = append(s.actions, found...)
+	return found
+}
+
+// Flush is called once the response is complete. It returns the actions Feed
+// could not return: tags left open before a later complete action, and
+// fenced blocks outside the tags already parsed.
+func (s *StreamParser) Flush() []Action {
+	text := s.buf.String()
+	found := s.parser.parseTags(text[s.offset:])
+	found = append(found, s.parser.parseBlocks(s.prose.String()+text[s.tagEnd:])...)
+	s.offset = len(text)
+
+	s.actions = append(s.actions, found...)
+	return found
+}
+
+// Actions returns every action found so far
+func (s *StreamParser) Actions() []Action {
+	return s.actions
+}
+
+// nextActionTag finds the first opening tag of one of the actions at or after
+// offset
+func nextActionTag(text string, offset int, tags []string) (int, string) {
+	start, found := -1, ""
+	for _, tag := range tags {
+		i := strings.Index(text[offset:], "<"+tag+">")
+		if i >= 0 && (start < 0 || offset+i < start) {
+			start, found = offset+i, tag
+		}
+	}
+	return start, found
+}
+
+// isSafeAction reports whether an action has no side effects, so it may run
+// before the response that requested it is complete
+func isSafeAction(action Action) bool {
+	_, ok := action.(*ReadFileAction)
+	return ok
+}
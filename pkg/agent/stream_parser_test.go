@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamParser_Feed(t *testing.T) {
+	response := "Let me look first.\n\n<read_file>\n<path>main.go</path>\n</read_file>\n\n" +
+		"Then build it:\n<execute_command>\n<command>go build</command>\n</execute_command>\n" +
+		"A <create_directory> mention in prose, then <create_directory><path>out</path></create_directory>"
+
+	stream := NewStreamParser(NewActionParser())
+
+	// Feed a few bytes at a time so tags are split across chunks
+	var detectedAt []int
+	for i := 0; i < len(response); i += 3 {
+		end := min(i+3, len(response))
+		for range stream.Feed(response[i:end]) {
+			detectedAt = append(detectedAt, end)
+		}
+	}
+	stream.Flush()
+
+	actions := stream.Actions()
+	if len(actions) != 3 {
+		t.Fatalf("Expected 3 actions, got %d", len(actions))
+	}
+	if read, ok := actions[0].(*ReadFileAction); !ok || read.Path != "main.go" {
+		t.Errorf("Expected READ_FILE main.go first, got %s", actions[0].String())
+	}
+	if cmd, ok := actions[1].(*ExecuteCommandAction); !ok || cmd.Command != "go build" {
+		t.Errorf("Expected EXECUTE_COMMAND go build second, got %s", actions[1].String())
+	}
+	if dir, ok := actions[2].(*CreateDirectoryAction); !ok || dir.Path != "out" {
+		t.Errorf("Expected CREATE_DIRECTORY out third, got %s", actions[2].String())
+	}
+
+	// The read is complete long before the response is
+	if len(detectedAt) < 2 || detectedAt[0] >= len(response)/2 {
+		t.Errorf("Expected the read action to be detected early, got %v", detectedAt)
+	}
+}
+
+func TestStreamParser_FlushFindsBlocks(t *testing.T) {
+	response := "```json\n[{\"path\": \"a.txt\", \"content\": \"hello\"}]\n```\n" +
+		"<read_file>\n<path>b.txt</path>\n</read_file>\n"
+
+	stream := NewStreamParser(NewActionParser())
+	if found := stream.Feed(response); len(found) != 1 {
+		t.Fatalf("Expected Feed to return the tag action only, got %d", len(found))
+	}
+	if found := stream.Flush(); len(found) != 1 {
+		t.Fatalf("Expected Flush to return the JSON action, got %d", len(found))
+	}
+	if len(stream.Actions()) != 2 {
+		t.Errorf("Expected 2 actions in total, got %d", len(stream.Actions()))
+	}
+
+	// A nested fenced block is file content, not an action of its own
+	stream = NewStreamParser(NewActionParser())
+	stream.Feed("<create_file>\n<path>doc.md</path>\n<content>\n```json\n[{\"path\": \"x\", \"content\": \"y\"}]\n```\n</content>\n</create_file>")
+	stream.Flush()
+	if len(stream.Actions()) != 1 {
+		t.Errorf("Expected only the create_file action, got %d", len(stream.Actions()))
+	}
+}
+
+func TestExecutor_Prefetched(t *testing.T) {
+	read := &ReadFileAction{Path: "missing.txt"}
+	other := &ReadFileAction{Path: "missing.txt"}
+
+	executor := NewExecutor(t.TempDir())
+	executor.SetPrefetched(map[Action]error{read: nil})
+	results := executor.run(context.Background(), []Action{read, other})
+
+	if results[0].Err != nil {
+		t.Errorf("Expected the prefetched result to be used, got %v", results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("Expected the other action to run and fail")
+	}
+
+	executor.SetPrefetched(map[Action]error{read: errors.New("boom")})
+	if results := executor.run(context.Background(), []Action{read}); results[0].Err == nil {
+		t.Error("Expected the prefetched error to be reported")
+	}
+}
+
+func TestAgent_EagerReadsWaitForReview(t *testing.T) {
+	workDir := t.TempDir()
+	os.WriteFile(filepath.Join(workDir, "secret.txt"), []byte("secret"), 0644)
+
+	agent := &Agent{workDir: workDir, out: io.Discard, eagerReads: true, autoExecuteActions: true}
+	agent.SetApprover(func(Action, string) (ReviewDecision, Action) { return ReviewSkip, nil })
+	agent.actionDetected(context.Background(), &ReadFileAction{Path: "secret.txt"})
+	if len(agent.prefetched) != 0 {
+		t.Error("Expected no read before the approver has decided")
+	}
+
+	agent.SetApprover(nil)
+	agent.SetReviewActions(true)
+	agent.actionDetected(context.Background(), &ReadFileAction{Path: "secret.txt"})
+	if len(agent.prefetched) != 0 {
+		t.Error("Expected no read before the review")
+	}
+
+	agent.SetReviewActions(false)
+	agent.actionDetected(context.Background(), &ReadFileAction{Path: "secret.txt"})
+	if len(agent.prefetched) != 1 {
+		t.Error("Expected the read to run early without review")
+	}
+}
@@ -1,14 +1,13 @@
 package ollama
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
+	"net/url"
 	"time"
 )
 
@@ -16,6 +15,9 @@ import (
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	headers    http.Header
+	retry      RetryPolicy
+	breaker    breaker
 }
 
 // NewClient creates a new Ollama API client
@@ -25,6 +27,9 @@ func NewClient(baseURL string) *Client {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		headers: make(http.Header),
+		retry:   DefaultRetryPolicy(),
+		breaker: breaker{threshold: defaultBreakerFailures, cooldown: defaultBreakerCooldown},
 	}
 }
 
@@ -35,6 +40,17 @@ func (c *Client) SetTimeout(d time.Duration) {
 	c.httpClient.Timeout = d
 }
 
+// SetHeader sets a header sent with every request, such as an Authorization
+// header for an Ollama server behind an authenticating proxy. An empty value
+// removes the header.
+func (c *Client) SetHeader(key, value string) {
+	if value == "" {
+		c.headers.Del(key)
+		return
+	}
+	c.headers.Set(key, value)
+}
+
 // Common Types
 
 // ModelConfig represents the model configuration
@@ -49,29 +65,85 @@ type ModelConfig struct {
 	StopWords   []string `json:"stop,omitempty"`        // Stop words for text generation
 }
 
-// ChatMessage represents a message in the chat
+// ChatMessage represents a message in the chat. Assistant messages may carry
+// ToolCalls; messages with the "tool" role carry the result of a call and name
+// the tool in ToolName.
 type ChatMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	Thinking  string     `json:"thinking,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"`
+}
+
+// Tool Calling Types
+
+// Tool describes a function the model may call
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function and its parameters
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  ToolParameters `json:"parameters"`
+}
+
+// ToolParameters is the JSON schema of a function's arguments
+type ToolParameters struct {
+	Type       string                  `json:"type"` // always "object"
+	Required   []string                `json:"required,omitempty"`
+	Properties map[string]ToolProperty `json:"properties"`
+}
+
+// ToolProperty is the JSON schema of a single function argument
+type ToolProperty struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+}
+
+// ToolCall represents a function call requested by the model
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the name and arguments of a requested call
+type ToolCallFunction struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
 }
 
 // Chat API Types
 
-// ChatRequest represents a request to the chat API
+// ChatRequest represents a request to the chat API. Stream is always sent
+// because the server streams by default when it is omitted.
 type ChatRequest struct {
 	Model    string        `json:"model"`
 	Messages []ChatMessage `json:"messages"`
-	Stream   bool          `json:"stream,omitempty"`
+	Tools    []Tool        `json:"tools,omitempty"`
+	Stream   bool          `json:"stream"`
+	Think    bool          `json:"think,omitempty"`
 	Format   string        `json:"format,omitempty"`
 	Options  *ModelConfig  `json:"options,omitempty"`
 }
 
 // ChatResponse represents a response from the chat API
 type ChatResponse struct {
-	Model     string `json:"model"`
-	Response  string `json:"response"`
-	Done      bool   `json:"done"`
-	CreatedAt string `json:"created_at"`
+	Model              string      `json:"model"`
+	Message            ChatMessage `json:"message"`
+	Response           string      `json:"response"`
+	Done               bool        `json:"done"`
+	DoneReason         string      `json:"done_reason,omitempty"`
+	CreatedAt          string      `json:"created_at"`
+	TotalDuration      int64       `json:"total_duration"`
+	LoadDuration       int64       `json:"load_duration"`
+	PromptEvalCount    int         `json:"prompt_eval_count"`
+	PromptEvalDuration int64       `json:"prompt_eval_duration"`
+	EvalCount          int         `json:"eval_count"`
+	EvalDuration       int64       `json:"eval_duration"`
 }
 
 // Generate API Types
@@ -85,6 +157,7 @@ type GenerateRequest struct {
 	Context  []int        `json:"context,omitempty"`
 	Stream   bool         `json:"stream,omitempty"`
 	Raw      bool         `json:"raw,omitempty"`
+	Think    bool         `json:"think,omitempty"`
 	Format   string       `json:"format,omitempty"`
 	Options  *ModelConfig `json:"options,omitempty"`
 }
@@ -93,6 +166,7 @@ type GenerateRequest struct {
 type GenerateResponse struct {
 	Model              string `json:"model"`
 	Response           string `json:"response"`
+	Thinking           string `json:"thinking,omitempty"`
 	Done               bool   `json:"done"`
 	Context            []int  `json:"context,omitempty"`
 	CreatedAt          string `json:"created_at"`
@@ -145,12 +219,23 @@ type ModelDetails struct {
 
 // ShowModelResponse represents the response from showing model details
 type ShowModelResponse struct {
-	License    string       `json:"license"`
-	ModelFile  string       `json:"modelfile"`
-	Parameters string       `json:"parameters"`
-	Template   string       `json:"template"`
-	System     string       `json:"system"`
-	Details    ModelDetails `json:"details"`
+	License      string       `json:"license"`
+	ModelFile    string       `json:"modelfile"`
+	Parameters   string       `json:"parameters"`
+	Template     string       `json:"template"`
+	System       string       `json:"system"`
+	Details      ModelDetails `json:"details"`
+	Capabilities []string     `json:"capabilities,omitempty"` // eg. "completion", "tools"
+}
+
+// HasCapability reports whether the model advertises the given capability
+func (r *ShowModelResponse) HasCapability(name string) bool {
+	for _, c := range r.Capabilities {
+		if c == name {
+			return true
+		}
+	}
+	return false
 }
 
 // CopyModelRequest represents a request to copy a model
@@ -170,13 +255,8 @@ type PullModelRequest struct {
 	Insecure bool   `json:"insecure,omitempty"`
 }
 
-// PullModelResponse represents the response from pulling a model
-type PullModelResponse struct {
-	Status    string `json:"status"`
-	Digest    string `json:"digest,omitempty"`
-	Total     int64  `json:"total,omitempty"`
-	Completed int64  `json:"completed,omitempty"`
-}
+// PullModelResponse represents the final status of pulling a model
+type PullModelResponse = ProgressResponse
 
 // PushModelRequest represents a request to push a model
 type PushModelRequest struct {
@@ -191,12 +271,39 @@ type CreateModelRequest struct {
 	ModelFile string `json:"modelfile"`
 }
 
+// RunningModel describes a model loaded into memory
+type RunningModel struct {
+	Name      string       `json:"name"`
+	Model     string       `json:"model"`
+	Size      int64        `json:"size"`
+	Digest    string       `json:"digest"`
+	Details   ModelDetails `json:"details"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	SizeVRAM  int64        `json:"size_vram"`
+}
+
+// ListRunningModelsResponse represents the response from listing running models
+type ListRunningModelsResponse struct {
+	Models []RunningModel `json:"models"`
+}
+
+// VersionResponse represents the response from the version endpoint
+type VersionResponse struct {
+	Version string `json:"version"`
+}
+
 // Chat API Methods
 
 // CreateChatCompletion sends a chat completion request to the Ollama API
 func (c *Client) CreateChatCompletion(req *ChatRequest) (*ChatResponse, error) {
+	return c.CreateChatCompletionWithContext(context.Background(), req)
+}
+
+// CreateChatCompletionWithContext is CreateChatCompletion with a context for cancellation and
+// deadlines
+func (c *Client) CreateChatCompletionWithContext(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
 	resp := &ChatResponse{}
-	err := c.sendRequest(http.MethodPost, "/api/chat", req, resp)
+	err := c.sendRequest(ctx, http.MethodPost, "/api/chat", req, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -207,8 +314,14 @@ func (c *Client) CreateChatCompletion(req *ChatRequest) (*ChatResponse, error) {
 
 // CreateGeneration sends a generate request to the Ollama API
 func (c *Client) CreateGeneration(req *GenerateRequest) (*GenerateResponse, error) {
+	return c.CreateGenerationWithContext(context.Background(), req)
+}
+
+// CreateGenerationWithContext is CreateGeneration with a context for cancellation and
+// deadlines
+func (c *Client) CreateGenerationWithContext(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
 	resp := &GenerateResponse{}
-	err := c.sendRequest(http.MethodPost, "/api/generate", req, resp)
+	err := c.sendRequest(ctx, http.MethodPost, "/api/generate", req, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -219,414 +332,50 @@ func (c *Client) CreateGeneration(req *GenerateRequest) (*GenerateResponse, erro
 // provided callback. The callback is invoked for each chunk (partial text).
 // If the callback returns an error, streaming stops and that error is returned.
 func (c *Client) StreamGenerate(req *GenerateRequest, onChunk func(string) error) error {
-	// Ensure streaming is enabled
-	req.Stream = true
-
-	data, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// debug output req
-	fmt.Println(req)
-
-	url := c.baseURL + "/api/generate"
-	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-
-	// If server returned non-200, read body and return error
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	// Read streaming body line-by-line
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		// Try to parse JSON chunk, but accept raw text as fallback
-		var chunk struct {
-			Response string `json:"response"`
-			Delta    string `json:"delta"`
-			Done     bool   `json:"done"`
-			Error    string `json:"error"`
-		}
-
-		if err := json.Unmarshal([]byte(line), &chunk); err == nil {
-			// prefer Delta if present
-			part := chunk.Response
-			if part == "" {
-				part = chunk.Delta
-			}
-			// Fallback: some Ollama/streaming formats use other keys (eg. "text" or "content").
-			// Try to extract a string from the raw JSON if response/delta are empty.
-			if part == "" {
-				if f := extractTextFromJSON(line); f != "" {
-					part = f
-				}
-			}
-			if chunk.Error != "" {
-				_ = resp.Body.Close()
-				return fmt.Errorf("stream error: %s", chunk.Error)
-			}
-			if part != "" {
-				if err := onChunk(part); err != nil {
-					_ = resp.Body.Close()
-					return err
-				}
-			}
-			if chunk.Done {
-				_ = resp.Body.Close()
-				return nil
-			}
-			continue
-		}
-
-		// Not JSON — treat as raw chunk
-		if err := onChunk(line); err != nil {
-			_ = resp.Body.Close()
-			return err
-		}
-	}
-
-	// scanner finished — check error
-	if err := scanner.Err(); err != nil {
-		_ = resp.Body.Close()
-		return fmt.Errorf("error reading stream: %w", err)
-	}
-
-	_ = resp.Body.Close()
-	return nil
+	return c.StreamGenerateWithContext(context.Background(), req, onChunk)
 }
 
 // StreamGenerateWithContext streams generate responses and accepts a
 // context.Context so the caller can control cancellation and deadlines.
 func (c *Client) StreamGenerateWithContext(ctx context.Context, reqBody *GenerateRequest, onChunk func(string) error) error {
-	reqBody.Stream = true
-
-	data, err := json.Marshal(reqBody)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := c.GenerateEvents(ctx, reqBody)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	url := c.baseURL + "/api/generate"
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(data))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		var chunk struct {
-			Response string `json:"response"`
-			Delta    string `json:"delta"`
-			Done     bool   `json:"done"`
-			Error    string `json:"error"`
-		}
-
-		if err := json.Unmarshal([]byte(line), &chunk); err == nil {
-			part := chunk.Response
-			if part == "" {
-				part = chunk.Delta
-			}
-			// Fallback to other common keys if response/delta empty
-			if part == "" {
-				if f := extractTextFromJSON(line); f != "" {
-					part = f
-				}
-			}
-			if chunk.Error != "" {
-				_ = resp.Body.Close()
-				return fmt.Errorf("stream error: %s", chunk.Error)
-			}
-			if part != "" {
-				if err := onChunk(part); err != nil {
-					_ = resp.Body.Close()
-					return err
-				}
-			}
-			if chunk.Done {
-				_ = resp.Body.Close()
-				return nil
-			}
-			continue
-		}
-
-		if err := onChunk(line); err != nil {
-			_ = resp.Body.Close()
-			return err
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		_ = resp.Body.Close()
-		return fmt.Errorf("error reading stream: %w", err)
+		return err
 	}
-
-	_ = resp.Body.Close()
-	return nil
+	return forwardText(ctx, events, onChunk)
 }
 
 // StreamChat streams chat responses similarly to StreamGenerate.
 func (c *Client) StreamChat(req *ChatRequest, onChunk func(string) error) error {
-	req.Stream = true
-
-	data, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	url := c.baseURL + "/api/chat"
-	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		var chunk struct {
-			Response string `json:"response"`
-			Delta    string `json:"delta"`
-			Done     bool   `json:"done"`
-			Error    string `json:"error"`
-		}
-
-		if err := json.Unmarshal([]byte(line), &chunk); err == nil {
-			part := chunk.Response
-			if part == "" {
-				part = chunk.Delta
-			}
-			// Fallback to other common keys if response/delta empty
-			if part == "" {
-				if f := extractTextFromJSON(line); f != "" {
-					part = f
-				}
-			}
-			if chunk.Error != "" {
-				_ = resp.Body.Close()
-				return fmt.Errorf("stream error: %s", chunk.Error)
-			}
-			if part != "" {
-				if err := onChunk(part); err != nil {
-					_ = resp.Body.Close()
-					return err
-				}
-			}
-			if chunk.Done {
-				_ = resp.Body.Close()
-				return nil
-			}
-			continue
-		}
-
-		if err := onChunk(line); err != nil {
-			_ = resp.Body.Close()
-			return err
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		_ = resp.Body.Close()
-		return fmt.Errorf("error reading stream: %w", err)
-	}
-
-	_ = resp.Body.Close()
-	return nil
+	return c.StreamChatWithContext(context.Background(), req, onChunk)
 }
 
 // StreamChatWithContext streams chat responses and accepts a context for
 // cancellation and deadline control.
 func (c *Client) StreamChatWithContext(ctx context.Context, reqBody *ChatRequest, onChunk func(string) error) error {
-	reqBody.Stream = true
-
-	data, err := json.Marshal(reqBody)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	url := c.baseURL + "/api/chat"
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(data))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := c.ChatEvents(ctx, reqBody)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.httpClient.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		_ = resp.Body.Close()
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
-	}
-
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		var chunk struct {
-			Response string `json:"response"`
-			Delta    string `json:"delta"`
-			Done     bool   `json:"done"`
-			Error    string `json:"error"`
-		}
-
-		if err := json.Unmarshal([]byte(line), &chunk); err == nil {
-			part := chunk.Response
-			if part == "" {
-				part = chunk.Delta
-			}
-			// Fallback to other common keys if response/delta empty
-			if part == "" {
-				if f := extractTextFromJSON(line); f != "" {
-					part = f
-				}
-			}
-			if chunk.Error != "" {
-				_ = resp.Body.Close()
-				return fmt.Errorf("stream error: %s", chunk.Error)
-			}
-			if part != "" {
-				if err := onChunk(part); err != nil {
-					_ = resp.Body.Close()
-					return err
-				}
-			}
-			if chunk.Done {
-				_ = resp.Body.Close()
-				return nil
-			}
-			continue
-		}
-
-		if err := onChunk(line); err != nil {
-			_ = resp.Body.Close()
-			return err
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		_ = resp.Body.Close()
-		return fmt.Errorf("error reading stream: %w", err)
+		return err
 	}
-
-	_ = resp.Body.Close()
-	return nil
-}
-
-// extractTextFromJSON attempts to pull a reasonable text chunk out of a
-// streaming JSON line when the known fields (response/delta) are empty.
-// This handles slight variations in streaming formats (eg. "text", "content",
-// or nested structures under "choices" / "message").
-func extractTextFromJSON(line string) string {
-	var m map[string]interface{}
-	if err := json.Unmarshal([]byte(line), &m); err != nil {
-		return ""
-	}
-
-	// common top-level keys
-	if v, ok := m["text"].(string); ok && v != "" {
-		return v
-	}
-	if v, ok := m["content"].(string); ok && v != "" {
-		return v
-	}
-
-	// choices array (common in some streaming formats)
-	if choices, ok := m["choices"].([]interface{}); ok && len(choices) > 0 {
-		for _, ch := range choices {
-			if chm, ok := ch.(map[string]interface{}); ok {
-				if t, ok := chm["text"].(string); ok && t != "" {
-					return t
-				}
-				if delta, ok := chm["delta"].(map[string]interface{}); ok {
-					if c, ok := delta["content"].(string); ok && c != "" {
-						return c
-					}
-					if c, ok := delta["text"].(string); ok && c != "" {
-						return c
-					}
-				}
-				if msg, ok := chm["message"].(map[string]interface{}); ok {
-					if content, ok := msg["content"].(string); ok && content != "" {
-						return content
-					}
-				}
-			}
-		}
-	}
-
-	// fallback: first string value we find in the top-level map
-	for _, v := range m {
-		switch s := v.(type) {
-		case string:
-			if s != "" {
-				return s
-			}
-		case map[string]interface{}:
-			for _, v2 := range s {
-				if s2, ok := v2.(string); ok && s2 != "" {
-					return s2
-				}
-			}
-		case []interface{}:
-			for _, e := range s {
-				if s2, ok := e.(string); ok && s2 != "" {
-					return s2
-				}
-			}
-		}
-	}
-
-	return ""
+	return forwardText(ctx, events, onChunk)
 }
 
 // Embeddings API Methods
 
 // CreateEmbeddings sends an embeddings request to the Ollama API
 func (c *Client) CreateEmbeddings(req *EmbeddingsRequest) (*EmbeddingsResponse, error) {
+	return c.CreateEmbeddingsWithContext(context.Background(), req)
+}
+
+// CreateEmbeddingsWithContext is CreateEmbeddings with a context for cancellation and
+// deadlines
+func (c *Client) CreateEmbeddingsWithContext(ctx context.Context, req *EmbeddingsRequest) (*EmbeddingsResponse, error) {
 	resp := &EmbeddingsResponse{}
-	err := c.sendRequest(http.MethodPost, "/api/embeddings", req, resp)
+	err := c.sendRequest(ctx, http.MethodPost, "/api/embeddings", req, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -637,8 +386,14 @@ func (c *Client) CreateEmbeddings(req *EmbeddingsRequest) (*EmbeddingsResponse,
 
 // ListModels lists all available models
 func (c *Client) ListModels() (*ListModelsResponse, error) {
+	return c.ListModelsWithContext(context.Background())
+}
+
+// ListModelsWithContext is ListModels with a context for cancellation and
+// deadlines
+func (c *Client) ListModelsWithContext(ctx context.Context) (*ListModelsResponse, error) {
 	resp := &ListModelsResponse{}
-	err := c.sendRequest(http.MethodGet, "/api/tags", nil, resp)
+	err := c.sendRequest(ctx, http.MethodGet, "/api/tags", nil, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -647,9 +402,15 @@ func (c *Client) ListModels() (*ListModelsResponse, error) {
 
 // ShowModel shows details of a specific model
 func (c *Client) ShowModel(name string) (*ShowModelResponse, error) {
+	return c.ShowModelWithContext(context.Background(), name)
+}
+
+// ShowModelWithContext is ShowModel with a context for cancellation and
+// deadlines
+func (c *Client) ShowModelWithContext(ctx context.Context, name string) (*ShowModelResponse, error) {
 	req := struct{ Name string }{Name: name}
 	resp := &ShowModelResponse{}
-	err := c.sendRequest(http.MethodPost, "/api/show", &req, resp)
+	err := c.sendRequest(ctx, http.MethodPost, "/api/show", &req, resp)
 	if err != nil {
 		return nil, err
 	}
@@ -658,63 +419,204 @@ func (c *Client) ShowModel(name string) (*ShowModelResponse, error) {
 
 // CopyModel copies a model
 func (c *Client) CopyModel(req *CopyModelRequest) error {
-	return c.sendRequest(http.MethodPost, "/api/copy", req, nil)
+	return c.CopyModelWithContext(context.Background(), req)
+}
+
+// CopyModelWithContext is CopyModel with a context for cancellation and
+// deadlines
+func (c *Client) CopyModelWithContext(ctx context.Context, req *CopyModelRequest) error {
+	return c.sendRequest(ctx, http.MethodPost, "/api/copy", req, nil)
 }
 
 // DeleteModel deletes a model
 func (c *Client) DeleteModel(req *DeleteModelRequest) error {
-	return c.sendRequest(http.MethodDelete, "/api/delete", req, nil)
+	return c.DeleteModelWithContext(context.Background(), req)
+}
+
+// DeleteModelWithContext is DeleteModel with a context for cancellation and
+// deadlines
+func (c *Client) DeleteModelWithContext(ctx context.Context, req *DeleteModelRequest) error {
+	return c.sendRequest(ctx, http.MethodDelete, "/api/delete", req, nil)
 }
 
-// PullModel pulls a model from a registry
+// PullModel pulls a model from a registry and returns the last status line,
+// "success" once the model is complete. Use StreamPullModel to follow the
+// progress.
 func (c *Client) PullModel(req *PullModelRequest) (*PullModelResponse, error) {
-	resp := &PullModelResponse{}
-	err := c.sendRequest(http.MethodPost, "/api/pull", req, resp)
+	return c.PullModelWithContext(context.Background(), req)
+}
+
+// PullModelWithContext is PullModel with a context for cancellation and
+// deadlines
+func (c *Client) PullModelWithContext(ctx context.Context, req *PullModelRequest) (*PullModelResponse, error) {
+	return c.streamProgress(ctx, "/api/pull", req, nil)
+}
+
+// PushModel pushes a model to a registry. Use StreamPushModel to follow the
+// progress.
+func (c *Client) PushModel(req *PushModelRequest) error {
+	return c.PushModelWithContext(context.Background(), req)
+}
+
+// PushModelWithContext is PushModel with a context for cancellation and
+// deadlines
+func (c *Client) PushModelWithContext(ctx context.Context, req *PushModelRequest) error {
+	_, err := c.streamProgress(ctx, "/api/push", req, nil)
+	return err
+}
+
+// CreateModel creates a new model. Use StreamCreateModel to follow the
+// progress.
+func (c *Client) CreateModel(req *CreateModelRequest) error {
+	return c.CreateModelWithContext(context.Background(), req)
+}
+
+// CreateModelWithContext is CreateModel with a context for cancellation and
+// deadlines
+func (c *Client) CreateModelWithContext(ctx context.Context, req *CreateModelRequest) error {
+	_, err := c.streamProgress(ctx, "/api/create", req, nil)
+	return err
+}
+
+// ListRunningModels lists the models currently loaded into memory
+func (c *Client) ListRunningModels() (*ListRunningModelsResponse, error) {
+	return c.ListRunningModelsWithContext(context.Background())
+}
+
+// ListRunningModelsWithContext is ListRunningModels with a context for
+// cancellation and deadlines
+func (c *Client) ListRunningModelsWithContext(ctx context.Context) (*ListRunningModelsResponse, error) {
+	resp := &ListRunningModelsResponse{}
+	err := c.sendRequest(ctx, http.MethodGet, "/api/ps", nil, resp)
 	if err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
-// PushModel pushes a model to a registry
-func (c *Client) PushModel(req *PushModelRequest) error {
-	return c.sendRequest(http.MethodPost, "/api/push", req, nil)
+// Blob Methods
+
+// BlobExists reports whether the server has the blob with the given digest,
+// e.g. "sha256:4f2b…"
+func (c *Client) BlobExists(digest string) (bool, error) {
+	return c.BlobExistsWithContext(context.Background(), digest)
 }
 
-// CreateModel creates a new model
-func (c *Client) CreateModel(req *CreateModelRequest) error {
-	return c.sendRequest(http.MethodPost, "/api/create", req, nil)
+// BlobExistsWithContext is BlobExists with a context for cancellation and
+// deadlines
+func (c *Client) BlobExistsWithContext(ctx context.Context, digest string) (bool, error) {
+	if digest == "" {
+		return false, fmt.Errorf("blob digest is required")
+	}
+	req, err := c.newRequest(ctx, http.MethodHead, "/api/blobs/"+url.PathEscape(digest), nil, "")
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.send(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send request: %w", err)
+	}
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// CreateBlob uploads the contents of r as the blob with the given digest,
+// which the server checks against the data; models are created from files
+// uploaded this way
+func (c *Client) CreateBlob(digest string, r io.Reader) error {
+	return c.CreateBlobWithContext(context.Background(), digest, r)
+}
+
+// CreateBlobWithContext is CreateBlob with a context for cancellation and
+// deadlines
+func (c *Client) CreateBlobWithContext(ctx context.Context, digest string, r io.Reader) error {
+	if digest == "" {
+		return fmt.Errorf("blob digest is required")
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, "/api/blobs/"+url.PathEscape(digest), r, "application/octet-stream")
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+// Server Methods
+
+// Version returns the version of the Ollama server
+func (c *Client) Version() (*VersionResponse, error) {
+	return c.VersionWithContext(context.Background())
+}
+
+// VersionWithContext is Version with a context for cancellation and deadlines
+func (c *Client) VersionWithContext(ctx context.Context) (*VersionResponse, error) {
+	resp := &VersionResponse{}
+	err := c.sendRequest(ctx, http.MethodGet, "/api/version", nil, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
 
 // Helper Methods
 
-// sendRequest is a helper function to send HTTP requests
-func (c *Client) sendRequest(method, endpoint string, reqBody interface{}, response interface{}) error {
-	var data []byte
-	var err error
+// newRequest builds a request for endpoint with the client's custom headers.
+// contentType is set if the request has a body.
+func (c *Client) newRequest(ctx context.Context, method, endpoint string, body io.Reader, contentType string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range c.headers {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if body != nil && contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
 
+// newJSONRequest builds a request for endpoint with reqBody encoded as JSON,
+// or no body if reqBody is nil
+func (c *Client) newJSONRequest(ctx context.Context, method, endpoint string, reqBody interface{}) (*http.Request, error) {
+	var body io.Reader
 	if reqBody != nil {
-		data, err = json.Marshal(reqBody)
+		data, err := json.Marshal(reqBody)
 		if err != nil {
-			return fmt.Errorf("failed to marshal request: %w", err)
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
 		}
+		body = bytes.NewReader(data)
 	}
+	return c.newRequest(ctx, method, endpoint, body, "application/json")
+}
 
-	url := c.baseURL + endpoint
-	var resp *http.Response
-
-	if method == http.MethodPost {
-		resp, err = c.httpClient.Post(url, "application/json", bytes.NewBuffer(data))
-	} else {
-		resp, err = c.httpClient.Get(url)
+// sendRequest sends a request with a JSON body, if reqBody is not nil, and
+// decodes the JSON response into response, if it is not nil
+func (c *Client) sendRequest(ctx context.Context, method, endpoint string, reqBody interface{}, response interface{}) error {
+	req, err := c.newJSONRequest(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return err
 	}
+	return c.do(req, response)
+}
 
+// do sends req and decodes the JSON response into response, if it is not nil.
+// Any 2xx status is a success; the blob endpoint answers 201 Created.
+func (c *Client) do(req *http.Request, response interface{}) error {
+	resp, err := c.send(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
 	}
@@ -0,0 +1,152 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShowModelWithContext_Cancelled(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := NewClient(server.URL).ShowModelWithContext(ctx, "m")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the request to give up at the deadline, took %v", elapsed)
+	}
+}
+
+func TestSendRequest_Method(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, body))
+		if r.URL.Path == "/api/tags" {
+			fmt.Fprint(w, `{"models":[{"name":"m"}]}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ctx := context.Background()
+	list, err := client.ListModelsWithContext(ctx)
+	if err != nil || len(list.Models) != 1 {
+		t.Fatalf("Unexpected model list: %+v, %v", list, err)
+	}
+	if err := client.DeleteModelWithContext(ctx, &DeleteModelRequest{Name: "m"}); err != nil {
+		t.Fatalf("DeleteModelWithContext failed: %v", err)
+	}
+
+	expected := []string{"GET /api/tags ", `DELETE /api/delete {"name":"m"}`}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Errorf("Expected requests %q, got %q", expected, requests)
+	}
+}
+
+func TestSetHeader(t *testing.T) {
+	var auth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/version":
+			fmt.Fprint(w, `{"version":"0.6.2"}`)
+		case "/api/chat":
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetHeader("Authorization", "Bearer secret")
+	if _, err := client.Version(); err != nil {
+		t.Fatalf("Version failed: %v", err)
+	}
+	events, err := client.ChatEvents(context.Background(), &ChatRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("ChatEvents failed: %v", err)
+	}
+	for range events {
+	}
+	client.SetHeader("Authorization", "")
+	client.Version()
+
+	expected := []string{"Bearer secret", "Bearer secret", ""}
+	if fmt.Sprint(auth) != fmt.Sprint(expected) {
+		t.Errorf("Expected Authorization headers %q, got %q", expected, auth)
+	}
+}
+
+func TestServerEndpoints(t *testing.T) {
+	const digest = "sha256:29fdb92e57cf0827ded04ae6461b5931d01fa595843f55d36f5b275a52087dd2"
+	blobs := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/ps":
+			fmt.Fprint(w, `{"models":[{"name":"llama3:8b","model":"llama3:8b","size":5137025024,"size_vram":5137025024,"expires_at":"2024-06-04T14:38:31.83753-07:00"}]}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/version":
+			fmt.Fprint(w, `{"version":"0.6.2"}`)
+		case r.Method == http.MethodHead && r.URL.Path == "/api/blobs/"+digest:
+			if _, ok := blobs[digest]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/api/blobs/"+digest:
+			if ct := r.Header.Get("Content-Type"); ct != "application/octet-stream" {
+				t.Errorf("Expected an octet stream, got %q", ct)
+			}
+			data, _ := io.ReadAll(r.Body)
+			blobs[digest] = string(data)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL)
+
+	running, err := client.ListRunningModels()
+	if err != nil || len(running.Models) != 1 {
+		t.Fatalf("Unexpected running models: %+v, %v", running, err)
+	}
+	if m := running.Models[0]; m.Name != "llama3:8b" || m.SizeVRAM != 5137025024 || m.ExpiresAt.IsZero() {
+		t.Errorf("Unexpected running model: %+v", m)
+	}
+
+	version, err := client.Version()
+	if err != nil || version.Version != "0.6.2" {
+		t.Errorf("Unexpected version: %+v, %v", version, err)
+	}
+
+	if exists, err := client.BlobExists(digest); err != nil || exists {
+		t.Fatalf("Expected no blob, got %v, %v", exists, err)
+	}
+	if err := client.CreateBlob(digest, strings.NewReader("weights")); err != nil {
+		t.Fatalf("CreateBlob failed: %v", err)
+	}
+	if blobs[digest] != "weights" {
+		t.Errorf("Expected the blob contents to be uploaded, got %q", blobs[digest])
+	}
+	if exists, err := client.BlobExists(digest); err != nil || !exists {
+		t.Errorf("Expected the blob to exist, got %v, %v", exists, err)
+	}
+	if err := client.CreateBlob("", strings.NewReader("")); err == nil {
+		t.Error("Expected an error for an empty digest")
+	}
+}
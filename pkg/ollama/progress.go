@@ -0,0 +1,122 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ProgressResponse is one status line streamed while a model is pulled,
+// pushed or created. Total and Completed count the bytes of the layer named
+// by Digest; they are zero for steps without a transfer, such as
+// "verifying sha256 digest".
+type ProgressResponse struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+}
+
+// ProgressFunc receives each status line of a pull, push or create. If it
+// returns an error, the operation's stream is closed and that error returned.
+type ProgressFunc func(ProgressResponse) error
+
+// progressLine is a streamed status line, or an error reported by the server
+// part way through
+type progressLine struct {
+	ProgressResponse
+	Error string `json:"error,omitempty"`
+}
+
+// StreamPullModel pulls a model from a registry and reports each status line
+// to onProgress. Pulls can take a long time; disable the client timeout with
+// SetTimeout(0) and cancel through StreamPullModelWithContext instead.
+func (c *Client) StreamPullModel(req *PullModelRequest, onProgress ProgressFunc) error {
+	return c.StreamPullModelWithContext(context.Background(), req, onProgress)
+}
+
+// StreamPullModelWithContext is StreamPullModel with a context for
+// cancellation and deadlines
+func (c *Client) StreamPullModelWithContext(ctx context.Context, req *PullModelRequest, onProgress ProgressFunc) error {
+	_, err := c.streamProgress(ctx, "/api/pull", req, onProgress)
+	return err
+}
+
+// StreamPushModel pushes a model to a registry and reports each status line
+// to onProgress
+func (c *Client) StreamPushModel(req *PushModelRequest, onProgress ProgressFunc) error {
+	return c.StreamPushModelWithContext(context.Background(), req, onProgress)
+}
+
+// StreamPushModelWithContext is StreamPushModel with a context for
+// cancellation and deadlines
+func (c *Client) StreamPushModelWithContext(ctx context.Context, req *PushModelRequest, onProgress ProgressFunc) error {
+	_, err := c.streamProgress(ctx, "/api/push", req, onProgress)
+	return err
+}
+
+// StreamCreateModel creates a model and reports each status line to
+// onProgress
+func (c *Client) StreamCreateModel(req *CreateModelRequest, onProgress ProgressFunc) error {
+	return c.StreamCreateModelWithContext(context.Background(), req, onProgress)
+}
+
+// StreamCreateModelWithContext is StreamCreateModel with a context for
+// cancellation and deadlines
+func (c *Client) StreamCreateModelWithContext(ctx context.Context, req *CreateModelRequest, onProgress ProgressFunc) error {
+	_, err := c.streamProgress(ctx, "/api/create", req, onProgress)
+	return err
+}
+
+// streamProgress posts reqBody to endpoint, passes each streamed status line
+// to onProgress, which may be nil, and returns the last one. The operation
+// has failed if the server reports an error on any line.
+func (c *Client) streamProgress(ctx context.Context, endpoint string, reqBody interface{}, onProgress ProgressFunc) (*ProgressResponse, error) {
+	httpReq, err := c.newJSONRequest(ctx, http.MethodPost, endpoint, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.send(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var last *ProgressResponse
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var progress progressLine
+		if err := json.Unmarshal([]byte(line), &progress); err != nil {
+			return nil, fmt.Errorf("failed to decode progress: %w", err)
+		}
+		if progress.Error != "" {
+			return nil, fmt.Errorf("stream error: %s", progress.Error)
+		}
+		last = &progress.ProgressResponse
+		if onProgress != nil {
+			if err := onProgress(progress.ProgressResponse); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading stream: %w", err)
+	}
+	if last == nil {
+		return nil, fmt.Errorf("no progress received")
+	}
+	return last, nil
+}
@@ -0,0 +1,103 @@
+package ollama
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newProgressServer returns a server that streams lines in response to any
+// request and records the decoded request body in got
+func newProgressServer(t *testing.T, got *map[string]interface{}, lines ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(got)
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStreamPullModel(t *testing.T) {
+	var got map[string]interface{}
+	server := newProgressServer(t, &got,
+		`{"status":"pulling manifest"}`,
+		`{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a0746a1ec1a","total":4000,"completed":1000}`,
+		`{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a0746a1ec1a","total":4000,"completed":4000}`,
+		`{"status":"verifying sha256 digest"}`,
+		`{"status":"success"}`,
+	)
+	client := NewClient(server.URL)
+
+	var progress []ProgressResponse
+	err := client.StreamPullModel(&PullModelRequest{Name: "llama3"}, func(p ProgressResponse) error {
+		progress = append(progress, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamPullModel failed: %v", err)
+	}
+	if got["name"] != "llama3" {
+		t.Errorf("Expected a pull of llama3, got %v", got)
+	}
+	if len(progress) != 5 || progress[1].Completed != 1000 || progress[1].Total != 4000 || progress[1].Digest != "sha256:6a0746a1ec1a" {
+		t.Errorf("Unexpected progress: %+v", progress)
+	}
+
+	// The non-streaming form reads the whole stream and returns the last line
+	resp, err := client.PullModel(&PullModelRequest{Name: "llama3"})
+	if err != nil || resp.Status != "success" {
+		t.Errorf("Expected success, got %+v, %v", resp, err)
+	}
+}
+
+func TestStreamPullModel_Error(t *testing.T) {
+	var got map[string]interface{}
+	server := newProgressServer(t, &got,
+		`{"status":"pulling manifest"}`,
+		`{"error":"pull model manifest: file does not exist"}`,
+	)
+
+	err := NewClient(server.URL).StreamPullModel(&PullModelRequest{Name: "missing"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "file does not exist") {
+		t.Errorf("Expected the server's error, got %v", err)
+	}
+}
+
+func TestStreamPushAndCreateModel(t *testing.T) {
+	var got map[string]interface{}
+	server := newProgressServer(t, &got,
+		`{"status":"retrieving manifest"}`,
+		`{"status":"success"}`,
+	)
+	client := NewClient(server.URL)
+
+	var statuses []string
+	record := func(p ProgressResponse) error {
+		statuses = append(statuses, p.Status)
+		return nil
+	}
+	if err := client.StreamPushModel(&PushModelRequest{Name: "me/model"}, record); err != nil {
+		t.Fatalf("StreamPushModel failed: %v", err)
+	}
+	if err := client.StreamCreateModel(&CreateModelRequest{Name: "mine", ModelFile: "FROM llama3"}, record); err != nil {
+		t.Fatalf("StreamCreateModel failed: %v", err)
+	}
+	if got["modelfile"] != "FROM llama3" {
+		t.Errorf("Expected the modelfile to be sent, got %v", got)
+	}
+	if strings.Join(statuses, ",") != "retrieving manifest,success,retrieving manifest,success" {
+		t.Errorf("Unexpected statuses: %v", statuses)
+	}
+
+	// An error from the callback stops the operation
+	stop := fmt.Errorf("stop")
+	err := client.StreamPushModel(&PushModelRequest{Name: "me/model"}, func(ProgressResponse) error { return stop })
+	if err != stop {
+		t.Errorf("Expected the callback's error, got %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrServerUnavailable is returned without contacting the server while the
+// circuit breaker is open, after several requests in a row have failed
+var ErrServerUnavailable = errors.New("server unavailable")
+
+// RetryPolicy controls how requests that fail transiently are retried: on a
+// refused or reset connection, or with status 429, 502, 503 or 504, which
+// Ollama returns while it loads a model. Only requests that are safe to send
+// twice are retried, and streams only until the response starts; nothing is
+// retried once tokens have been received.
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts in total, including the first; 1 or less disables retries
+	BaseDelay   time.Duration // Delay before the first retry, doubled for each one after
+	MaxDelay    time.Duration // Longest delay, including one asked for with Retry-After
+}
+
+// DefaultRetryPolicy returns the retry policy of new clients: three attempts,
+// half a second apart and then one second, plus jitter
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+// Default circuit breaker settings of new clients
+const (
+	defaultBreakerFailures = 3
+	defaultBreakerCooldown = 10 * time.Second
+)
+
+// SetRetryPolicy sets how transient failures are retried. Use RetryPolicy{}
+// to disable retries.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p
+}
+
+// SetCircuitBreaker makes requests fail immediately with ErrServerUnavailable
+// for cooldown after failures attempts in a row could not reach the server,
+// retries included. Once the cooldown has passed one request is let through
+// to probe it. failures of 0 disables the breaker.
+func (c *Client) SetCircuitBreaker(failures int, cooldown time.Duration) {
+	c.breaker.configure(failures, cooldown)
+}
+
+// retryableEndpoints are the POST endpoints that are safe to send again: they
+// only read, or, like a pull, pick up where they left off
+var retryableEndpoints = map[string]bool{
+	"/api/chat":       true,
+	"/api/generate":   true,
+	"/api/embeddings": true,
+	"/api/show":       true,
+	"/api/pull":       true,
+}
+
+// canRetry reports whether req may be sent again
+func canRetry(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return retryableEndpoints[req.URL.Path]
+	default:
+		return false
+	}
+}
+
+// unavailable reports whether a request failed because the server could not
+// be reached or is temporarily unable to answer. Cancellation by the caller
+// does not count.
+func unavailable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isTimeout reports whether err is the HTTP client's timeout; those are not
+// retried, as each attempt would wait for the whole timeout again
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// send sends req through the circuit breaker, retrying it per the retry
+// policy if it is safe to. The response is returned as the server sent it,
+// whatever its status, once no more attempts are left.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	ctx := req.Context()
+	retry := c.retry.MaxAttempts > 1 && canRetry(req)
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.httpClient.Do(req)
+		failed := unavailable(ctx, resp, err)
+		if ctx.Err() == nil {
+			c.breaker.record(failed)
+		} else {
+			c.breaker.abandon()
+		}
+		if !failed || !retry || attempt >= c.retry.MaxAttempts || isTimeout(err) {
+			return resp, err
+		}
+
+		delay := c.retry.delay(attempt, resp)
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+		if err := c.breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rewind returns a copy of req with a fresh body for another attempt
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		next.Body = body
+	}
+	return next, nil
+}
+
+// delay returns how long to wait before retry number attempt: the server's
+// Retry-After if it sent one, or else the base delay doubled for each earlier
+// retry with up to half of it replaced by jitter. Both are capped at MaxDelay.
+func (p RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	d, ok := retryAfter(resp)
+	if !ok && p.BaseDelay > 0 {
+		d = p.BaseDelay << (attempt - 1)
+		if d <= 0 {
+			d = p.MaxDelay // overflowed
+		}
+		if p.MaxDelay > 0 && d > p.MaxDelay {
+			d = p.MaxDelay
+		}
+		d = d/2 + rand.N(d/2+1)
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// breaker is a circuit breaker that opens after a number of consecutive
+// failures and lets a single probe through once its cooldown has passed
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// configure sets the number of failures that open the breaker and how long
+// it stays open, and closes it
+func (b *breaker) configure(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = threshold
+	b.cooldown = cooldown
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// allow returns ErrServerUnavailable while the breaker is open, or while the
+// probe sent after the cooldown has not come back
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return nil
+	}
+	if wait := time.Until(b.openUntil); wait > 0 {
+		return fmt.Errorf("%w: %d attempts in a row failed, retrying in %s", ErrServerUnavailable, b.failures, wait.Round(time.Second))
+	}
+	if b.probing {
+		return fmt.Errorf("%w: waiting for the server to answer", ErrServerUnavailable)
+	}
+	b.probing = true
+	return nil
+}
+
+// abandon ends a probe the caller cancelled without counting it either way
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// record counts a request that reached the server, or failed to
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
@@ -0,0 +1,195 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fastRetries retries quickly so the tests don't wait
+var fastRetries = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+// newFlakyServer returns a server that answers the first failures requests
+// with 503 and the rest with ok, and counts the requests in calls
+func newFlakyServer(t *testing.T, failures int32, calls *int32, ok func(w http.ResponseWriter, r *http.Request)) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(calls, 1) <= failures {
+			http.Error(w, "loading model", http.StatusServiceUnavailable)
+			return
+		}
+		ok(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRetry_Idempotent(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) < 3 {
+			http.Error(w, "loading model", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"parameters":"num_ctx 4096"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetRetryPolicy(fastRetries)
+	info, err := client.ShowModel("m")
+	if err != nil || info.Parameters != "num_ctx 4096" {
+		t.Fatalf("Expected the third attempt to succeed, got %+v, %v", info, err)
+	}
+	if len(bodies) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(bodies))
+	}
+	// Every attempt sends the request body in full
+	for _, body := range bodies {
+		if body != `{"Name":"m"}` {
+			t.Errorf("Unexpected request body %q", body)
+		}
+	}
+}
+
+func TestRetry_GivesUp(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(t, 10, &calls, nil)
+
+	client := NewClient(server.URL)
+	client.SetRetryPolicy(fastRetries)
+	client.SetCircuitBreaker(0, 0)
+	if _, err := client.ListModels(); err == nil {
+		t.Fatal("Expected an error once the attempts run out")
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetry_NotIdempotent(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(t, 1, &calls, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"success"}`)
+	})
+
+	client := NewClient(server.URL)
+	client.SetRetryPolicy(fastRetries)
+	if err := client.PushModel(&PushModelRequest{Name: "me/model"}); err == nil {
+		t.Fatal("Expected a push to fail without a retry")
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 attempt, got %d", calls)
+	}
+}
+
+func TestRetry_StreamEstablishment(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(t, 1, &calls, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Hi"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	})
+
+	client := NewClient(server.URL)
+	client.SetRetryPolicy(fastRetries)
+	events, err := client.ChatEvents(context.Background(), &ChatRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("ChatEvents failed: %v", err)
+	}
+	var text string
+	for ev := range events {
+		text += ev.Text
+	}
+	if text != "Hi" || calls != 2 {
+		t.Errorf("Expected the stream to start on the second attempt, got %q after %d attempts", text, calls)
+	}
+}
+
+func TestRetry_CancelDuringBackoff(t *testing.T) {
+	var calls int32
+	server := newFlakyServer(t, 10, &calls, nil)
+
+	client := NewClient(server.URL)
+	client.SetRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.ListModelsWithContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to end the backoff, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected to give up at the deadline, took %v", elapsed)
+	}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, max := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 5: time.Second} {
+		for i := 0; i < 20; i++ {
+			if d := p.delay(attempt, nil); d < max/2 || d > max {
+				t.Errorf("Attempt %d: expected a delay between %v and %v, got %v", attempt, max/2, max, d)
+			}
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"0"}}}
+	if d := p.delay(1, resp); d != 0 {
+		t.Errorf("Expected Retry-After: 0 to retry at once, got %v", d)
+	}
+	resp.Header.Set("Retry-After", "120")
+	if d := p.delay(1, resp); d != time.Second {
+		t.Errorf("Expected Retry-After to be capped at MaxDelay, got %v", d)
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	if d := p.delay(1, resp); d != 0 {
+		t.Errorf("Expected a past Retry-After date to retry at once, got %v", d)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var down atomic.Bool
+	var calls int32
+	down.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"version":"0.6.2"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetRetryPolicy(RetryPolicy{})
+	client.SetCircuitBreaker(2, 50*time.Millisecond)
+
+	client.Version()
+	client.Version()
+	_, err := client.Version()
+	if !errors.Is(err, ErrServerUnavailable) {
+		t.Fatalf("Expected the breaker to open, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the open breaker not to contact the server, got %d requests", calls)
+	}
+
+	// After the cooldown a probe goes through and closes the breaker
+	down.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.Version(); err != nil {
+		t.Fatalf("Expected the probe to succeed, got %v", err)
+	}
+	if _, err := client.Version(); err != nil {
+		t.Errorf("Expected the breaker to be closed, got %v", err)
+	}
+}
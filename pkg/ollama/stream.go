@@ -0,0 +1,227 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// StreamEventType identifies the kind of a StreamEvent
+type StreamEventType string
+
+const (
+	// EventToken carries a piece of response text in Text
+	EventToken StreamEventType = "token"
+	// EventThinking carries a piece of the model's reasoning in Text
+	EventThinking StreamEventType = "thinking"
+	// EventToolCall carries a function call requested by the model in ToolCall
+	EventToolCall StreamEventType = "tool_call"
+	// EventDone is the last event of a successful stream and carries Stats
+	EventDone StreamEventType = "done"
+	// EventError is the last event of a failed stream and carries Err
+	EventError StreamEventType = "error"
+)
+
+// StreamEvent is a single typed event decoded from a streaming response
+type StreamEvent struct {
+	Type     StreamEventType
+	Text     string
+	ToolCall *ToolCall
+	Stats    *StreamStats
+	Err      error
+}
+
+// StreamStats holds the statistics reported with the final chunk of a stream
+type StreamStats struct {
+	Model              string
+	DoneReason         string
+	TotalDuration      time.Duration
+	LoadDuration       time.Duration
+	PromptEvalCount    int
+	PromptEvalDuration time.Duration
+	EvalCount          int
+	EvalDuration       time.Duration
+	Context            []int // generate API only
+}
+
+// TokensPerSecond returns the generation speed, or 0 if it is unknown
+func (s *StreamStats) TokensPerSecond() float64 {
+	if s.EvalDuration <= 0 {
+		return 0
+	}
+	return float64(s.EvalCount) / s.EvalDuration.Seconds()
+}
+
+// GenerateEvents sends a generate request and returns a channel of typed
+// events. The channel is closed after an EventDone or EventError event, or
+// when ctx is cancelled. An error is returned directly if the stream could not
+// be established.
+func (c *Client) GenerateEvents(ctx context.Context, req *GenerateRequest) (<-chan StreamEvent, error) {
+	req.Stream = true
+	return c.streamEvents(ctx, "/api/generate", req)
+}
+
+// ChatEvents sends a chat request and returns a channel of typed events. The
+// channel is closed after an EventDone or EventError event, or when ctx is
+// cancelled. An error is returned directly if the stream could not be
+// established.
+func (c *Client) ChatEvents(ctx context.Context, req *ChatRequest) (<-chan StreamEvent, error) {
+	req.Stream = true
+	return c.streamEvents(ctx, "/api/chat", req)
+}
+
+// streamEvents posts reqBody to endpoint and decodes the streamed lines into
+// events on a background goroutine.
+func (c *Client) streamEvents(ctx context.Context, endpoint string, reqBody interface{}) (<-chan StreamEvent, error) {
+	httpReq, err := c.newJSONRequest(ctx, http.MethodPost, endpoint, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		defer func() { _ = resp.Body.Close() }()
+
+		emit := func(ev StreamEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			done, ok := decodeStreamLine(line, emit)
+			if done || !ok {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			emit(StreamEvent{Type: EventError, Err: fmt.Errorf("error reading stream: %w", err)})
+			return
+		}
+
+		// The server closed the stream without a final chunk, so the reply
+		// may be cut short
+		emit(StreamEvent{Type: EventError, Err: fmt.Errorf("stream ended before done")})
+	}()
+
+	return events, nil
+}
+
+// forwardText passes the text of each token event to onChunk until the
+// stream is done. The caller cancels ctx to stop the stream once it returns.
+func forwardText(ctx context.Context, events <-chan StreamEvent, onChunk func(string) error) error {
+	for ev := range events {
+		switch ev.Type {
+		case EventToken:
+			if err := onChunk(ev.Text); err != nil {
+				return err
+			}
+		case EventError:
+			return ev.Err
+		case EventDone:
+			return nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("stream ended before done")
+}
+
+// streamChunk covers the fields of both generate and chat streaming chunks
+type streamChunk struct {
+	Model              string       `json:"model"`
+	Message            *ChatMessage `json:"message"`
+	Response           string       `json:"response"`
+	Thinking           string       `json:"thinking"`
+	Done               bool         `json:"done"`
+	DoneReason         string       `json:"done_reason"`
+	Context            []int        `json:"context"`
+	TotalDuration      int64        `json:"total_duration"`
+	LoadDuration       int64        `json:"load_duration"`
+	PromptEvalCount    int          `json:"prompt_eval_count"`
+	PromptEvalDuration int64        `json:"prompt_eval_duration"`
+	EvalCount          int          `json:"eval_count"`
+	EvalDuration       int64        `json:"eval_duration"`
+	Error              string       `json:"error"`
+}
+
+// decodeStreamLine turns one streamed line into events. It reports whether the
+// stream has finished and whether emitting succeeded (false once the consumer
+// has gone away).
+func decodeStreamLine(line string, emit func(StreamEvent) bool) (done bool, ok bool) {
+	var chunk streamChunk
+	if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+		// Not JSON — treat as raw text
+		return false, emit(StreamEvent{Type: EventToken, Text: line})
+	}
+
+	if chunk.Error != "" {
+		emit(StreamEvent{Type: EventError, Err: fmt.Errorf("stream error: %s", chunk.Error)})
+		return true, true
+	}
+
+	thinking, text := chunk.Thinking, chunk.Response
+	if chunk.Message != nil {
+		thinking, text = chunk.Message.Thinking, chunk.Message.Content
+	}
+
+	if thinking != "" && !emit(StreamEvent{Type: EventThinking, Text: thinking}) {
+		return false, false
+	}
+	if text != "" && !emit(StreamEvent{Type: EventToken, Text: text}) {
+		return false, false
+	}
+	if chunk.Message != nil {
+		for i := range chunk.Message.ToolCalls {
+			call := chunk.Message.ToolCalls[i]
+			if !emit(StreamEvent{Type: EventToolCall, ToolCall: &call}) {
+				return false, false
+			}
+		}
+	}
+
+	if !chunk.Done {
+		return false, true
+	}
+
+	return true, emit(StreamEvent{Type: EventDone, Stats: &StreamStats{
+		Model:              chunk.Model,
+		DoneReason:         chunk.DoneReason,
+		TotalDuration:      time.Duration(chunk.TotalDuration),
+		LoadDuration:       time.Duration(chunk.LoadDuration),
+		PromptEvalCount:    chunk.PromptEvalCount,
+		PromptEvalDuration: time.Duration(chunk.PromptEvalDuration),
+		EvalCount:          chunk.EvalCount,
+		EvalDuration:       time.Duration(chunk.EvalDuration),
+		Context:            chunk.Context,
+	}})
+}
@@ -0,0 +1,148 @@
+package ollama
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChatEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("Expected path /api/chat, got %s", r.URL.Path)
+		}
+		fmt.Fprintln(w, `{"model":"m","message":{"role":"assistant","thinking":"hmm"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"m","message":{"role":"assistant","content":"Hel"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"m","message":{"role":"assistant","content":"lo"},"done":false}`)
+		fmt.Fprintln(w, `{"model":"m","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"read_file","arguments":{"path":"a.go"}}}]},"done":false}`)
+		fmt.Fprintln(w, `{"model":"m","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","total_duration":2000000,"prompt_eval_count":12,"eval_count":5,"eval_duration":1000000000}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	events, err := client.ChatEvents(context.Background(), &ChatRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("ChatEvents failed: %v", err)
+	}
+
+	var types []StreamEventType
+	var text string
+	var last StreamEvent
+	for ev := range events {
+		types = append(types, ev.Type)
+		if ev.Type == EventToken {
+			text += ev.Text
+		}
+		last = ev
+	}
+
+	expected := []StreamEventType{EventThinking, EventToken, EventToken, EventToolCall, EventDone}
+	if fmt.Sprint(types) != fmt.Sprint(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, types)
+	}
+	if text != "Hello" {
+		t.Errorf("Expected text 'Hello', got '%s'", text)
+	}
+
+	stats := last.Stats
+	if stats.PromptEvalCount != 12 || stats.EvalCount != 5 {
+		t.Errorf("Unexpected token counts: %+v", stats)
+	}
+	if stats.TotalDuration != 2*time.Millisecond {
+		t.Errorf("Expected total duration 2ms, got %v", stats.TotalDuration)
+	}
+	if stats.TokensPerSecond() != 5 {
+		t.Errorf("Expected 5 tokens/s, got %v", stats.TokensPerSecond())
+	}
+}
+
+func TestGenerateEvents_StreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"response":"partial","done":false}`)
+		fmt.Fprintln(w, `{"error":"model crashed"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	events, err := client.GenerateEvents(context.Background(), &GenerateRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("GenerateEvents failed: %v", err)
+	}
+
+	var last StreamEvent
+	for ev := range events {
+		last = ev
+	}
+	if last.Type != EventError || last.Err == nil {
+		t.Fatalf("Expected final error event, got %+v", last)
+	}
+}
+
+func TestChatEvents_Truncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Half a"},"done":false}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	events, err := client.ChatEvents(context.Background(), &ChatRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("ChatEvents failed: %v", err)
+	}
+
+	var last StreamEvent
+	for ev := range events {
+		last = ev
+	}
+	if last.Type != EventError || last.Err == nil || last.Err.Error() != "stream ended before done" {
+		t.Fatalf("Expected a truncated stream to end with an error, got %+v", last)
+	}
+}
+
+func TestStreamChat(t *testing.T) {
+	var truncated atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Hel"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"lo"},"done":false}`)
+		if !truncated.Load() {
+			fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	var text string
+	onChunk := func(s string) error {
+		text += s
+		return nil
+	}
+	if err := client.StreamChat(&ChatRequest{Model: "m"}, onChunk); err != nil || text != "Hello" {
+		t.Fatalf("Expected %q, got %q, %v", "Hello", text, err)
+	}
+
+	truncated.Store(true)
+	if err := client.StreamChat(&ChatRequest{Model: "m"}, onChunk); err == nil {
+		t.Fatal("Expected a stream without a done chunk to fail")
+	}
+
+	stop := fmt.Errorf("stop")
+	if err := client.StreamChat(&ChatRequest{Model: "m"}, func(string) error { return stop }); err != stop {
+		t.Errorf("Expected the callback's error, got %v", err)
+	}
+}
+
+func TestGenerateEvents_BadStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not found", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if _, err := client.GenerateEvents(context.Background(), &GenerateRequest{Model: "m"}); err == nil {
+		t.Fatal("Expected an error for a 404 response")
+	}
+}
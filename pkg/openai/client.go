@@ -0,0 +1,393 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// Client talks to a server implementing the OpenAI chat completions API,
+// such as llama.cpp's server, vLLM or LM Studio. Requests, responses and
+// stream events use the ollama package's types, so the client can stand in
+// for an Ollama client.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the API at baseURL, which includes the
+// version prefix, e.g. "http://localhost:8080/v1"
+func NewClient(baseURL string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SetTimeout sets the underlying HTTP client's timeout. Use 0 to disable the
+// client-side timeout for streaming and cancel through a context instead.
+func (c *Client) SetTimeout(d time.Duration) {
+	c.httpClient.Timeout = d
+}
+
+// SetAPIKey sets the bearer token sent with every request
+func (c *Client) SetAPIKey(key string) {
+	c.apiKey = key
+}
+
+// Wire Types
+
+// message is a chat message in the OpenAI format
+type message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []toolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// toolCall is a function call; the arguments are a JSON document in a string
+type toolCall struct {
+	Index    *int   `json:"index,omitempty"` // streaming deltas only
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments,omitempty"`
+	} `json:"function"`
+}
+
+// responseFormat asks for JSON output
+type responseFormat struct {
+	Type string `json:"type"`
+}
+
+// streamOptions asks for token usage in the last chunk of a stream
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// chatRequest is the body of POST /chat/completions
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []message       `json:"messages"`
+	Tools          []ollama.Tool   `json:"tools,omitempty"`
+	Stream         bool            `json:"stream"`
+	StreamOptions  *streamOptions  `json:"stream_options,omitempty"`
+	Temperature    float64         `json:"temperature,omitempty"`
+	TopP           float64         `json:"top_p,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+// usage reports token counts
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// chatResponse is the body of a non-streaming chat completion
+type chatResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      message `json:"message"`
+		FinishReason string  `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *usage `json:"usage"`
+}
+
+// modelList is the body of GET /models. llama.cpp reports the context size
+// in meta.n_ctx_train and vLLM in max_model_len.
+type modelList struct {
+	Data []struct {
+		ID          string `json:"id"`
+		Created     int64  `json:"created"`
+		OwnedBy     string `json:"owned_by"`
+		MaxModelLen int    `json:"max_model_len"`
+		Meta        *struct {
+			NCtxTrain int `json:"n_ctx_train"`
+		} `json:"meta"`
+	} `json:"data"`
+}
+
+// embeddingsRequest is the body of POST /embeddings
+type embeddingsRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+// embeddingsResponse is the body returned by POST /embeddings
+type embeddingsResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// toMessages converts a conversation to the OpenAI format. Tool calls get
+// generated IDs that the tool results following them refer to; tool results
+// that don't answer a call, such as parsed action results, are sent as user
+// messages because the API rejects them otherwise.
+func toMessages(history []ollama.ChatMessage) ([]message, error) {
+	messages := make([]message, 0, len(history))
+	var open []string // IDs of calls still waiting for a result
+	for i, m := range history {
+		out := message{Role: m.Role, Content: m.Content}
+		switch m.Role {
+		case "assistant":
+			open = nil
+			for j, call := range m.ToolCalls {
+				args, err := json.Marshal(call.Function.Arguments)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encode tool call arguments: %w", err)
+				}
+				tc := toolCall{ID: fmt.Sprintf("call_%d_%d", i, j), Type: "function"}
+				tc.Function.Name = call.Function.Name
+				tc.Function.Arguments = string(args)
+				out.ToolCalls = append(out.ToolCalls, tc)
+				open = append(open, tc.ID)
+			}
+		case "tool":
+			if len(open) > 0 {
+				out.ToolCallID = open[0]
+				open = open[1:]
+			} else {
+				out.Role = "user"
+				out.Content = "Tool results:\n" + m.Content
+			}
+		}
+		messages = append(messages, out)
+	}
+	return messages, nil
+}
+
+// newChatRequest converts a chat request to the OpenAI format
+func newChatRequest(req *ollama.ChatRequest, stream bool) (*chatRequest, error) {
+	messages, err := toMessages(req.Messages)
+	if err != nil {
+		return nil, err
+	}
+	out := &chatRequest{
+		Model:    req.Model,
+		Messages: messages,
+		Tools:    req.Tools,
+		Stream:   stream,
+	}
+	if stream {
+		out.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+	if req.Options != nil {
+		out.Temperature = req.Options.Temperature
+		out.TopP = req.Options.TopP
+		out.Stop = req.Options.StopWords
+	}
+	if req.Format == "json" {
+		out.ResponseFormat = &responseFormat{Type: "json_object"}
+	}
+	return out, nil
+}
+
+// fromToolCalls converts tool calls to the ollama representation
+func fromToolCalls(calls []toolCall) ([]ollama.ToolCall, error) {
+	out := make([]ollama.ToolCall, 0, len(calls))
+	for _, call := range calls {
+		args := map[string]interface{}{}
+		if call.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+				return nil, fmt.Errorf("invalid arguments for tool call %s: %w", call.Function.Name, err)
+			}
+		}
+		out = append(out, ollama.ToolCall{Function: ollama.ToolCallFunction{Name: call.Function.Name, Arguments: args}})
+	}
+	return out, nil
+}
+
+// Chat API Methods
+
+// CreateChatCompletion sends a chat request and returns the complete reply
+func (c *Client) CreateChatCompletion(req *ollama.ChatRequest) (*ollama.ChatResponse, error) {
+	return c.CreateChatCompletionWithContext(context.Background(), req)
+}
+
+// CreateChatCompletionWithContext is CreateChatCompletion with a context for
+// cancellation and deadlines
+func (c *Client) CreateChatCompletionWithContext(ctx context.Context, req *ollama.ChatRequest) (*ollama.ChatResponse, error) {
+	body, err := newChatRequest(req, false)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var resp chatResponse
+	if err := c.sendRequest(ctx, http.MethodPost, "/chat/completions", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("response has no choices")
+	}
+
+	choice := resp.Choices[0]
+	calls, err := fromToolCalls(choice.Message.ToolCalls)
+	if err != nil {
+		return nil, err
+	}
+	out := &ollama.ChatResponse{
+		Model: resp.Model,
+		Message: ollama.ChatMessage{
+			Role:      "assistant",
+			Content:   choice.Message.Content,
+			ToolCalls: calls,
+		},
+		Done:          true,
+		DoneReason:    choice.FinishReason,
+		TotalDuration: int64(time.Since(start)),
+	}
+	if resp.Usage != nil {
+		out.PromptEvalCount = resp.Usage.PromptTokens
+		out.EvalCount = resp.Usage.CompletionTokens
+	}
+	return out, nil
+}
+
+// Embeddings API Methods
+
+// CreateEmbeddings returns the embedding of the request's prompt
+func (c *Client) CreateEmbeddings(req *ollama.EmbeddingsRequest) (*ollama.EmbeddingsResponse, error) {
+	return c.CreateEmbeddingsWithContext(context.Background(), req)
+}
+
+// CreateEmbeddingsWithContext is CreateEmbeddings with a context for
+// cancellation and deadlines
+func (c *Client) CreateEmbeddingsWithContext(ctx context.Context, req *ollama.EmbeddingsRequest) (*ollama.EmbeddingsResponse, error) {
+	var resp embeddingsResponse
+	err := c.sendRequest(ctx, http.MethodPost, "/embeddings", &embeddingsRequest{Model: req.Model, Input: req.Prompt}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("response has no embeddings")
+	}
+	return &ollama.EmbeddingsResponse{Embedding: resp.Data[0].Embedding}, nil
+}
+
+// Model Methods
+
+// ListModels lists the models the server offers
+func (c *Client) ListModels() (*ollama.ListModelsResponse, error) {
+	return c.ListModelsWithContext(context.Background())
+}
+
+// ListModelsWithContext is ListModels with a context for cancellation and
+// deadlines
+func (c *Client) ListModelsWithContext(ctx context.Context) (*ollama.ListModelsResponse, error) {
+	list, err := c.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := &ollama.ListModelsResponse{}
+	for _, m := range list.Data {
+		resp.Models = append(resp.Models, ollama.ModelInfo{
+			Name:       m.ID,
+			ModifiedAt: time.Unix(m.Created, 0),
+		})
+	}
+	return resp, nil
+}
+
+// ShowModel describes a model from the server's model list. The API has no
+// equivalent of Ollama's show endpoint, so only the context length is known,
+// and only if the server reports it. Tool support is not advertised; enable
+// native tools explicitly for servers that support them.
+func (c *Client) ShowModel(name string) (*ollama.ShowModelResponse, error) {
+	return c.ShowModelWithContext(context.Background(), name)
+}
+
+// ShowModelWithContext is ShowModel with a context for cancellation and
+// deadlines
+func (c *Client) ShowModelWithContext(ctx context.Context, name string) (*ollama.ShowModelResponse, error) {
+	list, err := c.listModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range list.Data {
+		if m.ID != name {
+			continue
+		}
+		resp := &ollama.ShowModelResponse{Capabilities: []string{"completion"}}
+		contextLength := m.MaxModelLen
+		if m.Meta != nil && m.Meta.NCtxTrain > 0 {
+			contextLength = m.Meta.NCtxTrain
+		}
+		if contextLength > 0 {
+			resp.Parameters = fmt.Sprintf("context_length: %d", contextLength)
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("model %q not found", name)
+}
+
+// listModels fetches the server's model list
+func (c *Client) listModels(ctx context.Context) (*modelList, error) {
+	var list modelList
+	if err := c.sendRequest(ctx, http.MethodGet, "/models", nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Helper Methods
+
+// newRequest builds a request to endpoint with a JSON body and the API key
+func (c *Client) newRequest(ctx context.Context, method, endpoint string, reqBody interface{}) (*http.Request, error) {
+	var body io.Reader
+	if reqBody != nil {
+		data, err := json.Marshal(reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return req, nil
+}
+
+// sendRequest sends a request and decodes the JSON response into response
+func (c *Client) sendRequest(ctx context.Context, method, endpoint string, reqBody interface{}, response interface{}) error {
+	req, err := c.newRequest(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+	if response != nil {
+		if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
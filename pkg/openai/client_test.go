@@ -0,0 +1,213 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+func TestChatEvents(t *testing.T) {
+	var got chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Expected path /v1/chat/completions, got %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Expected the API key, got %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"model":"m","choices":[{"delta":{"role":"assistant","reasoning_content":"hmm"}}]}`,
+			`{"model":"m","choices":[{"delta":{"content":"Hel"}}]}`,
+			`{"model":"m","choices":[{"delta":{"content":"lo"}}]}`,
+			`{"model":"m","choices":[{"delta":{"tool_calls":[{"index":0,"id":"c1","type":"function","function":{"name":"read_file","arguments":"{\"pa"}}]}}]}`,
+			`{"model":"m","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\"a.go\"}"}}]}}]}`,
+			`{"model":"m","choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+			`{"model":"m","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":5}}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL + "/v1/")
+	client.SetAPIKey("secret")
+	events, err := client.ChatEvents(context.Background(), &ollama.ChatRequest{
+		Model:    "m",
+		Messages: []ollama.ChatMessage{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil {
+		t.Fatalf("ChatEvents failed: %v", err)
+	}
+
+	var types []ollama.StreamEventType
+	var text string
+	var call *ollama.ToolCall
+	var last ollama.StreamEvent
+	for ev := range events {
+		types = append(types, ev.Type)
+		switch ev.Type {
+		case ollama.EventToken:
+			text += ev.Text
+		case ollama.EventToolCall:
+			call = ev.ToolCall
+		}
+		last = ev
+	}
+
+	want := []ollama.StreamEventType{ollama.EventThinking, ollama.EventToken, ollama.EventToken, ollama.EventToolCall, ollama.EventDone}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Fatalf("Expected events %v, got %v", want, types)
+	}
+	if text != "Hello" {
+		t.Errorf("Expected text %q, got %q", "Hello", text)
+	}
+	if call == nil || call.Function.Name != "read_file" || call.Function.Arguments["path"] != "a.go" {
+		t.Errorf("Unexpected tool call: %+v", call)
+	}
+	if s := last.Stats; s.PromptEvalCount != 12 || s.EvalCount != 5 || s.DoneReason != "tool_calls" || s.Model != "m" {
+		t.Errorf("Unexpected stats: %+v", s)
+	}
+
+	if !got.Stream || got.StreamOptions == nil || !got.StreamOptions.IncludeUsage {
+		t.Errorf("Expected a streaming request with usage, got %+v", got)
+	}
+}
+
+func TestChatEvents_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"error\":{\"message\":\"model overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	events, err := NewClient(server.URL).ChatEvents(context.Background(), &ollama.ChatRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("ChatEvents failed: %v", err)
+	}
+	var last ollama.StreamEvent
+	for ev := range events {
+		last = ev
+	}
+	if last.Type != ollama.EventError || last.Err == nil {
+		t.Errorf("Expected an error event, got %+v", last)
+	}
+}
+
+func TestChatEvents_Truncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Half a\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"c1\",\"function\":{\"name\":\"read_file\",\"arguments\":\"{}\"}}]}}]}\n\n")
+	}))
+	defer server.Close()
+
+	events, err := NewClient(server.URL).ChatEvents(context.Background(), &ollama.ChatRequest{Model: "m"})
+	if err != nil {
+		t.Fatalf("ChatEvents failed: %v", err)
+	}
+	var types []ollama.StreamEventType
+	var last ollama.StreamEvent
+	for ev := range events {
+		types = append(types, ev.Type)
+		last = ev
+	}
+	if last.Type != ollama.EventError || last.Err == nil || last.Err.Error() != "stream ended before done" {
+		t.Fatalf("Expected a cut-off stream to end with an error, got %+v", last)
+	}
+	for _, typ := range types {
+		if typ == ollama.EventToolCall {
+			t.Errorf("Expected the incomplete tool call not to be emitted, got %v", types)
+		}
+	}
+}
+
+func TestToMessages(t *testing.T) {
+	messages, err := toMessages([]ollama.ChatMessage{
+		{Role: "user", Content: "Read a.go"},
+		{Role: "assistant", ToolCalls: []ollama.ToolCall{
+			{Function: ollama.ToolCallFunction{Name: "read_file", Arguments: map[string]interface{}{"path": "a.go"}}},
+		}},
+		{Role: "tool", ToolName: "read_file", Content: "package a"},
+		{Role: "assistant", Content: "<read_file><path>b.go</path></read_file>"},
+		{Role: "tool", Content: "package b"},
+	})
+	if err != nil {
+		t.Fatalf("toMessages failed: %v", err)
+	}
+
+	call := messages[1].ToolCalls[0]
+	if call.ID == "" || call.Type != "function" || call.Function.Arguments != `{"path":"a.go"}` {
+		t.Errorf("Unexpected tool call: %+v", call)
+	}
+	if messages[2].Role != "tool" || messages[2].ToolCallID != call.ID {
+		t.Errorf("Expected the tool result to answer call %s, got %+v", call.ID, messages[2])
+	}
+	// Results of parsed action tags answer no call
+	if messages[4].Role != "user" || messages[4].Content != "Tool results:\npackage b" {
+		t.Errorf("Expected an orphan tool result to become a user message, got %+v", messages[4])
+	}
+}
+
+func TestCreateChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream || req.Temperature != 0.2 || req.ResponseFormat == nil {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+		fmt.Fprint(w, `{"model":"m","choices":[{"message":{"role":"assistant","content":"{}"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(server.URL).CreateChatCompletion(&ollama.ChatRequest{
+		Model:   "m",
+		Format:  "json",
+		Options: &ollama.ModelConfig{Temperature: 0.2},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion failed: %v", err)
+	}
+	if resp.Message.Content != "{}" || resp.DoneReason != "stop" || resp.PromptEvalCount != 3 || resp.EvalCount != 1 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+func TestModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/models":
+			fmt.Fprint(w, `{"data":[{"id":"llama","created":1700000000,"meta":{"n_ctx_train":8192}},{"id":"qwen","max_model_len":32768}]}`)
+		case "/embeddings":
+			fmt.Fprint(w, `{"data":[{"embedding":[0.5,0.25]}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(server.URL)
+
+	list, err := client.ListModels()
+	if err != nil || len(list.Models) != 2 || list.Models[0].Name != "llama" {
+		t.Fatalf("Unexpected model list: %+v, %v", list, err)
+	}
+
+	info, err := client.ShowModel("qwen")
+	if err != nil || info.Parameters != "context_length: 32768" || info.HasCapability("tools") {
+		t.Errorf("Unexpected model info: %+v, %v", info, err)
+	}
+	if _, err := client.ShowModel("missing"); err == nil {
+		t.Error("Expected an error for an unknown model")
+	}
+
+	emb, err := client.CreateEmbeddings(&ollama.EmbeddingsRequest{Model: "e", Prompt: "hi"})
+	if err != nil || len(emb.Embedding) != 2 {
+		t.Errorf("Unexpected embeddings: %+v, %v", emb, err)
+	}
+}
@@ -0,0 +1,217 @@
+package openai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// streamChunk is one Server-Sent Event of a streamed chat completion
+type streamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content          string     `json:"content"`
+			ReasoningContent string     `json:"reasoning_content"`
+			ToolCalls        []toolCall `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *usage `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ChatEvents sends a streaming chat request and returns a channel of typed
+// events, like ollama.Client.ChatEvents. Tool calls arrive in pieces and are
+// emitted once complete. The stats carry the token usage if the server
+// reports it, and durations measured by the client.
+func (c *Client) ChatEvents(ctx context.Context, req *ollama.ChatRequest) (<-chan ollama.StreamEvent, error) {
+	body, err := newChatRequest(req, true)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := c.newRequest(ctx, http.MethodPost, "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	events := make(chan ollama.StreamEvent)
+	go func() {
+		defer close(events)
+		defer func() { _ = resp.Body.Close() }()
+
+		emit := func(ev ollama.StreamEvent) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		s := &streamState{stats: &ollama.StreamStats{Model: req.Model}}
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			data, ok := strings.CutPrefix(line, "data:")
+			if !ok {
+				// Blank separators, comments and other SSE fields
+				continue
+			}
+			data = strings.TrimSpace(data)
+			if data == "[DONE]" {
+				s.finished = true
+				break
+			}
+			if ok, err := s.decode(data, emit); err != nil {
+				emit(ollama.StreamEvent{Type: ollama.EventError, Err: err})
+				return
+			} else if !ok {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			emit(ollama.StreamEvent{Type: ollama.EventError, Err: fmt.Errorf("error reading stream: %w", err)})
+			return
+		}
+		if !s.finished {
+			// The connection dropped before the reply was complete
+			emit(ollama.StreamEvent{Type: ollama.EventError, Err: fmt.Errorf("stream ended before done")})
+			return
+		}
+
+		if !s.flushToolCalls(emit) {
+			return
+		}
+		s.stats.TotalDuration = time.Since(start)
+		if !s.firstToken.IsZero() {
+			s.stats.EvalDuration = time.Since(s.firstToken)
+		}
+		emit(ollama.StreamEvent{Type: ollama.EventDone, Stats: s.stats})
+	}()
+
+	return events, nil
+}
+
+// streamState accumulates a stream: partial tool calls by index, the usage,
+// the time of the first generated token and whether the server finished it
+// with a finish_reason or [DONE]
+type streamState struct {
+	calls      map[int]*toolCall
+	stats      *ollama.StreamStats
+	firstToken time.Time
+	finished   bool
+}
+
+// decode turns one chunk into events. It returns false once the consumer has
+// gone away, and an error for malformed chunks or errors sent by the server.
+func (s *streamState) decode(data string, emit func(ollama.StreamEvent) bool) (bool, error) {
+	var chunk streamChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return false, fmt.Errorf("invalid stream chunk: %w", err)
+	}
+	if chunk.Error != nil {
+		return false, fmt.Errorf("stream error: %s", chunk.Error.Message)
+	}
+	if chunk.Model != "" {
+		s.stats.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		s.stats.PromptEvalCount = chunk.Usage.PromptTokens
+		s.stats.EvalCount = chunk.Usage.CompletionTokens
+	}
+
+	for _, choice := range chunk.Choices {
+		delta := choice.Delta
+		if s.firstToken.IsZero() && (delta.Content != "" || delta.ReasoningContent != "" || len(delta.ToolCalls) > 0) {
+			s.firstToken = time.Now()
+		}
+		if delta.ReasoningContent != "" && !emit(ollama.StreamEvent{Type: ollama.EventThinking, Text: delta.ReasoningContent}) {
+			return false, nil
+		}
+		if delta.Content != "" && !emit(ollama.StreamEvent{Type: ollama.EventToken, Text: delta.Content}) {
+			return false, nil
+		}
+		for _, part := range delta.ToolCalls {
+			s.addToolCall(part)
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			s.stats.DoneReason = *choice.FinishReason
+			s.finished = true
+		}
+	}
+	return true, nil
+}
+
+// addToolCall merges a piece of a streamed tool call into the call with the
+// same index; the name and ID arrive once and the arguments in fragments
+func (s *streamState) addToolCall(part toolCall) {
+	if s.calls == nil {
+		s.calls = make(map[int]*toolCall)
+	}
+	index := len(s.calls)
+	if part.Index != nil {
+		index = *part.Index
+	}
+	call, ok := s.calls[index]
+	if !ok {
+		call = &toolCall{}
+		s.calls[index] = call
+	}
+	if part.ID != "" {
+		call.ID = part.ID
+	}
+	if part.Function.Name != "" {
+		call.Function.Name = part.Function.Name
+	}
+	call.Function.Arguments += part.Function.Arguments
+}
+
+// flushToolCalls emits the accumulated tool calls in index order. It returns
+// false if the consumer has gone away or the calls could not be decoded.
+func (s *streamState) flushToolCalls(emit func(ollama.StreamEvent) bool) bool {
+	indices := make([]int, 0, len(s.calls))
+	for i := range s.calls {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	calls := make([]toolCall, 0, len(indices))
+	for _, i := range indices {
+		calls = append(calls, *s.calls[i])
+	}
+	converted, err := fromToolCalls(calls)
+	if err != nil {
+		emit(ollama.StreamEvent{Type: ollama.EventError, Err: err})
+		return false
+	}
+	for i := range converted {
+		if !emit(ollama.StreamEvent{Type: ollama.EventToolCall, ToolCall: &converted[i]}) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aykay76/llmapi/pkg/agent"
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// AgentFactory creates the agent of a new session, confined to workDir. If it
+// returns an error the session is not created.
+type AgentFactory func(workDir string) (*agent.Agent, error)
+
+// Server exposes agents over HTTP. Every session has its own agent,
+// conversation history and working directory under the server's root, so
+// several users can share one deployment without seeing each other's files.
+// Session IDs are the only credential, so sessions can't be listed.
+type Server struct {
+	root     string
+	newAgent AgentFactory
+	mux      *http.ServeMux
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// session is one conversation. Its lock is held while a request drives the
+// agent; a second request for the same session is refused rather than queued.
+type session struct {
+	id      string
+	created time.Time
+	agent   *agent.Agent
+	busy    sync.Mutex
+	events  *sseWriter // the stream of the request in progress, if any
+}
+
+// New creates a server that keeps session working directories under root
+// and creates their agents with newAgent
+func New(root string, newAgent AgentFactory) (*Server, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session root: %w", err)
+	}
+
+	s := &Server{
+		root:     root,
+		newAgent: newAgent,
+		mux:      http.NewServeMux(),
+		sessions: make(map[string]*session),
+	}
+	s.mux.HandleFunc("POST /sessions", s.handleCreate)
+	s.mux.HandleFunc("GET /sessions/{id}", s.handleGet)
+	s.mux.HandleFunc("DELETE /sessions/{id}", s.handleDelete)
+	s.mux.HandleFunc("POST /sessions/{id}/messages", s.handleMessage)
+	s.mux.HandleFunc("GET /sessions/{id}/history", s.handleHistory)
+	s.mux.HandleFunc("GET /sessions/{id}/actions", s.handleActions)
+	s.mux.HandleFunc("POST /sessions/{id}/actions/approve", s.handleApprove)
+	s.mux.HandleFunc("POST /sessions/{id}/actions/reject", s.handleReject)
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// sessionInfo describes a session in responses
+type sessionInfo struct {
+	ID             string    `json:"id"`
+	Created        time.Time `json:"created"`
+	WorkDir        string    `json:"workdir"`
+	Messages       int       `json:"messages"`
+	PendingActions int       `json:"pending_actions"`
+}
+
+// pendingAction describes an action waiting for approval
+type pendingAction struct {
+	Index  int    `json:"index"` // 1-based, as used by approve
+	Action string `json:"action"`
+	Type   string `json:"type,omitempty"`
+}
+
+// createRequest is the optional body of POST /sessions
+type createRequest struct {
+	SystemPrompt string `json:"system_prompt"`
+	AutoExecute  bool   `json:"auto_execute"`
+}
+
+// messageRequest is the body of POST /sessions/{id}/messages
+type messageRequest struct {
+	Content string `json:"content"`
+}
+
+// approveRequest is the optional body of POST /sessions/{id}/actions/approve
+type approveRequest struct {
+	Indices []int `json:"indices"` // 1-based; empty approves every action
+}
+
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := decodeOptional(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	workDir := filepath.Join(s.root, id)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create working directory: %w", err))
+		return
+	}
+
+	a, err := s.newAgent(workDir)
+	if err != nil {
+		os.RemoveAll(workDir)
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create agent: %w", err))
+		return
+	}
+	if req.SystemPrompt != "" {
+		a.SetSystemPrompt(req.SystemPrompt)
+	}
+	a.SetAutoExecuteActions(req.AutoExecute)
+
+	sess := &session{id: id, created: time.Now(), agent: a}
+	a.SetEventSink(agent.EventSinkFunc(sess.handleEvent))
+	s.mu.Lock()
+	s.sessions[id] = sess
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusCreated, sess.info())
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.acquire(w, r)
+	if !ok {
+		return
+	}
+	defer sess.busy.Unlock()
+	writeJSON(w, http.StatusOK, sess.info())
+}
+
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.acquire(w, r)
+	if !ok {
+		return
+	}
+	defer sess.busy.Unlock()
+
+	// The working directory goes with the session. Its lock is held, so no
+	// action is writing to it.
+	workDir := filepath.Join(s.root, sess.id)
+	if filepath.Dir(workDir) != s.root {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("working directory %s is outside the session root", workDir))
+		return
+	}
+	if err := os.RemoveAll(workDir); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to remove working directory: %w", err))
+		return
+	}
+
+	s.mu.Lock()
+	delete(s.sessions, sess.id)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.acquire(w, r)
+	if !ok {
+		return
+	}
+	defer sess.busy.Unlock()
+
+	history := sess.agent.History()
+	if history == nil {
+		history = []ollama.ChatMessage{}
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+func (s *Server) handleActions(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.acquire(w, r)
+	if !ok {
+		return
+	}
+	defer sess.busy.Unlock()
+	writeJSON(w, http.StatusOK, describePending(sess.agent.PendingActions()))
+}
+
+func (s *Server) handleMessage(w http.ResponseWriter, r *http.Request) {
+	var req messageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if req.Content == "" {
+		writeError(w, http.StatusBadRequest, errors.New("content is required"))
+		return
+	}
+
+	sess, ok := s.acquire(w, r)
+	if !ok {
+		return
+	}
+	defer sess.busy.Unlock()
+
+	sess.stream(w, func() error {
+		return sess.agent.SendMessage(r.Context(), req.Content, nil)
+	})
+}
+
+func (s *Server) handleApprove(w http.ResponseWriter, r *http.Request) {
+	var req approveRequest
+	if err := decodeOptional(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sess, ok := s.acquire(w, r)
+	if !ok {
+		return
+	}
+	defer sess.busy.Unlock()
+
+	pending := sess.agent.PendingActions()
+	if len(pending) == 0 {
+		writeError(w, http.StatusConflict, errors.New("no pending actions"))
+		return
+	}
+
+	// Actions left out of a partial approval are skipped, so the model is
+	// told they were not run. Actions the model sends afterwards run as they
+	// would without an approver: only auto-executing sessions run them.
+	if len(req.Indices) > 0 {
+		approved := make(map[agent.Action]bool)
+		for _, i := range req.Indices {
+			if i < 1 || i > len(pending) {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("action index %d out of range 1-%d", i, len(pending)))
+				return
+			}
+			approved[pending[i-1]] = true
+		}
+		waiting := make(map[agent.Action]bool, len(pending))
+		for _, action := range pending {
+			waiting[action] = true
+		}
+		sess.agent.SetApprover(func(action agent.Action, _ string) (agent.ReviewDecision, agent.Action) {
+			if waiting[action] && !approved[action] {
+				return agent.ReviewSkip, nil
+			}
+			return agent.ReviewApprove, nil
+		})
+		defer sess.agent.SetApprover(nil)
+	}
+
+	sess.stream(w, func() error {
+		return sess.agent.ExecutePendingActions(r.Context(), nil)
+	})
+}
+
+func (s *Server) handleReject(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.acquire(w, r)
+	if !ok {
+		return
+	}
+	defer sess.busy.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]int{"rejected": sess.agent.DiscardPendingActions()})
+}
+
+// acquire looks up the session named in the path and locks it, writing an
+// error response if it doesn't exist or is busy
+func (s *Server) acquire(w http.ResponseWriter, r *http.Request) (*session, bool) {
+	s.mu.Lock()
+	sess, ok := s.sessions[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("session %q not found", r.PathValue("id")))
+		return nil, false
+	}
+	if !sess.busy.TryLock() {
+		writeError(w, http.StatusConflict, errors.New("session is busy with another request"))
+		return nil, false
+	}
+	return sess, true
+}
+
+// info describes the session; the caller holds its lock
+func (sess *session) info() sessionInfo {
+	return sessionInfo{
+		ID:             sess.id,
+		Created:        sess.created,
+		WorkDir:        sess.agent.WorkDir(),
+		Messages:       len(sess.agent.History()),
+		PendingActions: len(sess.agent.PendingActions()),
+	}
+}
+
+// describePending lists actions with their 1-based indices
+func describePending(actions []agent.Action) []pendingAction {
+	pending := make([]pendingAction, len(actions))
+	for i, action := range actions {
+		pending[i] = pendingAction{Index: i + 1, Action: action.String()}
+		if named, ok := action.(agent.NamedAction); ok {
+			pending[i].Type = named.ActionName()
+		}
+	}
+	return pending
+}
+
+// decodeOptional decodes a JSON request body into v, leaving v unchanged if
+// the body is empty
+func decodeOptional(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// newSessionID returns a random, URL-safe session identifier
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as a JSON error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,297 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aykay76/llmapi/pkg/agent"
+	"github.com/aykay76/llmapi/pkg/ollama"
+)
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	name string
+	data string
+}
+
+// newTestServer returns a server whose model answers user messages with reply
+// and action results with "Done."
+func newTestServer(t *testing.T, reply string) *httptest.Server {
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		var req ollama.ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		content := "Done."
+		if req.Messages[len(req.Messages)-1].Role == "user" {
+			content = reply
+		}
+		encoded, _ := json.Marshal(content)
+		fmt.Fprintf(w, `{"message":{"role":"assistant","content":%s},"done":false}`+"\n", encoded)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	t.Cleanup(model.Close)
+
+	srv, err := New(t.TempDir(), func(workDir string) (*agent.Agent, error) {
+		return agent.New(ollama.NewClient(model.URL), agent.WithModel("test-model"), agent.WithWorkDir(workDir)), nil
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// do sends a request with an optional JSON body and decodes a JSON response
+// into out
+func do(t *testing.T, method, url, body string, wantStatus int, out interface{}) {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		var b bytes.Buffer
+		b.ReadFrom(resp.Body)
+		t.Fatalf("%s %s: expected status %d, got %d: %s", method, url, wantStatus, resp.StatusCode, b.String())
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Invalid response to %s %s: %v", method, url, err)
+		}
+	}
+}
+
+// streamEvents posts body to url and returns the Server-Sent Events
+func streamEvents(t *testing.T, url, body string) []sseEvent {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST %s: expected status 200, got %d", url, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %q", ct)
+	}
+
+	var events []sseEvent
+	var current sseEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			current.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			current.data = strings.TrimPrefix(line, "data: ")
+		case line == "":
+			events = append(events, current)
+			current = sseEvent{}
+		}
+	}
+	return events
+}
+
+// lastEvent returns the data of the last event, which must be "done"
+func lastEvent(t *testing.T, events []sseEvent) string {
+	if len(events) == 0 || events[len(events)-1].name != "done" {
+		t.Fatalf("Expected the stream to end with a done event, got %v", events)
+	}
+	return events[len(events)-1].data
+}
+
+func TestServer_MessageAndApprove(t *testing.T) {
+	ts := newTestServer(t, "Let me check. <read_file><path>notes.txt</path></read_file> <read_file><path>other.txt</path></read_file>")
+
+	var info sessionInfo
+	do(t, "POST", ts.URL+"/sessions", "", http.StatusCreated, &info)
+	if info.ID == "" || info.WorkDir == "" {
+		t.Fatalf("Unexpected session: %+v", info)
+	}
+	os.WriteFile(filepath.Join(info.WorkDir, "notes.txt"), []byte("hello"), 0644)
+
+	events := streamEvents(t, ts.URL+"/sessions/"+info.ID+"/messages", `{"content":"What is in notes.txt?"}`)
+	names := make(map[string]int)
+	for _, ev := range events {
+		names[ev.name]++
+	}
+	if names["turn_started"] != 1 || names["token"] == 0 || names["actions"] != 1 {
+		t.Errorf("Unexpected events: %v", names)
+	}
+	var done doneEvent
+	json.Unmarshal([]byte(lastEvent(t, events)), &done)
+	if len(done.PendingActions) != 2 || done.PendingActions[0].Type != "read_file" || done.Error != "" {
+		t.Fatalf("Unexpected done event: %+v", done)
+	}
+
+	var pending []pendingAction
+	do(t, "GET", ts.URL+"/sessions/"+info.ID+"/actions", "", http.StatusOK, &pending)
+	if len(pending) != 2 || pending[1].Index != 2 {
+		t.Fatalf("Unexpected pending actions: %+v", pending)
+	}
+
+	// Approving only the first action skips the second
+	events = streamEvents(t, ts.URL+"/sessions/"+info.ID+"/actions/approve", `{"indices":[1]}`)
+	var results struct {
+		Results []struct {
+			Status string `json:"status"`
+		} `json:"results"`
+		PendingActions []pendingAction `json:"pending_actions"`
+	}
+	json.Unmarshal([]byte(lastEvent(t, events)), &results)
+	if len(results.Results) != 2 || results.Results[0].Status != "succeeded" || results.Results[1].Status != "skipped" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+	if len(results.PendingActions) != 0 {
+		t.Errorf("Expected no pending actions, got %+v", results.PendingActions)
+	}
+
+	var history []ollama.ChatMessage
+	do(t, "GET", ts.URL+"/sessions/"+info.ID+"/history", "", http.StatusOK, &history)
+	roles := make([]string, len(history))
+	for i, m := range history {
+		roles[i] = m.Role
+	}
+	if got := strings.Join(roles, ","); got != "user,assistant,tool,assistant" {
+		t.Errorf("Unexpected history roles: %s", got)
+	}
+	if !strings.Contains(history[2].Content, "hello") {
+		t.Errorf("Expected the file contents in the tool message, got %q", history[2].Content)
+	}
+}
+
+func TestServer_PartialApproveFollowUp(t *testing.T) {
+	// The model asks for two files, then for a third once it has the results
+	model := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollama.ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		assistant := 0
+		for _, m := range req.Messages {
+			if m.Role == "assistant" {
+				assistant++
+			}
+		}
+		content := "Done."
+		switch assistant {
+		case 0:
+			content = "<read_file><path>a.txt</path></read_file> <read_file><path>b.txt</path></read_file>"
+		case 1:
+			content = "<read_file><path>c.txt</path></read_file>"
+		}
+		encoded, _ := json.Marshal(content)
+		fmt.Fprintf(w, `{"message":{"role":"assistant","content":%s},"done":false}`+"\n", encoded)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	defer model.Close()
+
+	srv, err := New(t.TempDir(), func(workDir string) (*agent.Agent, error) {
+		return agent.New(ollama.NewClient(model.URL), agent.WithModel("test-model"), agent.WithWorkDir(workDir)), nil
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	var info sessionInfo
+	do(t, "POST", ts.URL+"/sessions", "", http.StatusCreated, &info)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		os.WriteFile(filepath.Join(info.WorkDir, name), []byte(name), 0644)
+	}
+	streamEvents(t, ts.URL+"/sessions/"+info.ID+"/messages", `{"content":"Read the files"}`)
+
+	// Actions the model sends after the approved batch run automatically
+	srv.sessions[info.ID].agent.SetAutoExecuteActions(true)
+	events := streamEvents(t, ts.URL+"/sessions/"+info.ID+"/actions/approve", `{"indices":[1]}`)
+	var done doneEvent
+	json.Unmarshal([]byte(lastEvent(t, events)), &done)
+	var statuses []string
+	for _, r := range done.Results {
+		statuses = append(statuses, string(r.Status))
+	}
+	if got := strings.Join(statuses, ","); got != "succeeded,skipped,succeeded" {
+		t.Errorf("Expected only the unapproved action to be skipped, got %s", got)
+	}
+}
+
+func TestServer_Reject(t *testing.T) {
+	ts := newTestServer(t, "<read_file><path>notes.txt</path></read_file>")
+
+	var info sessionInfo
+	do(t, "POST", ts.URL+"/sessions", `{"system_prompt":"Be brief."}`, http.StatusCreated, &info)
+	streamEvents(t, ts.URL+"/sessions/"+info.ID+"/messages", `{"content":"Read notes.txt"}`)
+
+	var rejected map[string]int
+	do(t, "POST", ts.URL+"/sessions/"+info.ID+"/actions/reject", "", http.StatusOK, &rejected)
+	if rejected["rejected"] != 1 {
+		t.Errorf("Expected 1 rejected action, got %v", rejected)
+	}
+	do(t, "POST", ts.URL+"/sessions/"+info.ID+"/actions/approve", "", http.StatusConflict, nil)
+}
+
+func TestServer_SessionIsolation(t *testing.T) {
+	ts := newTestServer(t, "Hi.")
+
+	var first, second sessionInfo
+	do(t, "POST", ts.URL+"/sessions", "", http.StatusCreated, &first)
+	do(t, "POST", ts.URL+"/sessions", "", http.StatusCreated, &second)
+	if first.ID == second.ID || first.WorkDir == second.WorkDir {
+		t.Fatalf("Expected separate sessions, got %+v and %+v", first, second)
+	}
+
+	streamEvents(t, ts.URL+"/sessions/"+first.ID+"/messages", `{"content":"Hello"}`)
+
+	var a, b sessionInfo
+	do(t, "GET", ts.URL+"/sessions/"+first.ID, "", http.StatusOK, &a)
+	do(t, "GET", ts.URL+"/sessions/"+second.ID, "", http.StatusOK, &b)
+	if a.Messages != 2 || b.Messages != 0 {
+		t.Errorf("Expected histories of 2 and 0 messages, got %d and %d", a.Messages, b.Messages)
+	}
+
+	// Sessions can't be enumerated by other clients
+	do(t, "GET", ts.URL+"/sessions", "", http.StatusMethodNotAllowed, nil)
+
+	if err := os.WriteFile(filepath.Join(first.WorkDir, "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	do(t, "DELETE", ts.URL+"/sessions/"+first.ID, "", http.StatusNoContent, nil)
+	do(t, "GET", ts.URL+"/sessions/"+first.ID, "", http.StatusNotFound, nil)
+	if _, err := os.Stat(first.WorkDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the working directory to be removed, got %v", err)
+	}
+	if _, err := os.Stat(second.WorkDir); err != nil {
+		t.Errorf("Expected the other session's directory to be kept, got %v", err)
+	}
+	do(t, "POST", ts.URL+"/sessions/"+second.ID+"/messages", `{}`, http.StatusBadRequest, nil)
+}
+
+func TestServer_CreateFailure(t *testing.T) {
+	root := t.TempDir()
+	srv, err := New(root, func(workDir string) (*agent.Agent, error) {
+		return nil, fmt.Errorf("invalid policy")
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	do(t, "POST", ts.URL+"/sessions", "", http.StatusInternalServerError, nil)
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Errorf("Expected no working directory to be left behind, got %d", len(entries))
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aykay76/llmapi/pkg/agent"
+)
+
+// sseWriter writes Server-Sent Events to a response
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// send writes one event with v encoded as JSON in its data field. Write
+// errors are ignored; a client that went away cancels the request instead.
+func (s *sseWriter) send(event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data)
+	s.flusher.Flush()
+}
+
+// doneEvent is the last event of every stream
+type doneEvent struct {
+	Error          string               `json:"error,omitempty"`
+	Results        []agent.ActionResult `json:"results,omitempty"`
+	PendingActions []pendingAction      `json:"pending_actions"`
+}
+
+// handleEvent forwards an agent event to the stream in progress
+func (sess *session) handleEvent(ev agent.Event) {
+	if sess.events != nil {
+		sess.events.send(string(ev.Type), ev)
+	}
+}
+
+// stream runs fn with the agent's events sent to the client as Server-Sent
+// Events, followed by a "done" event with the results of any actions and the
+// actions still waiting for approval. The caller holds the session's lock.
+func (sess *session) stream(w http.ResponseWriter, fn func() error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	sess.events = &sseWriter{w: w, flusher: flusher}
+	defer func() { sess.events = nil }()
+
+	done := doneEvent{}
+	if err := fn(); err != nil {
+		done.Error = err.Error()
+	}
+	done.Results = sess.agent.TurnResults()
+	done.PendingActions = describePending(sess.agent.PendingActions())
+	sess.events.send("done", done)
+}